@@ -2,17 +2,21 @@ module github.com/fabien-marty/slog-helpers
 
 go 1.21.7
 
-require github.com/mattn/go-isatty v0.0.20
+require (
+	github.com/fabien-marty/tracerr v0.0.0-20240624051446-7f090eca46ee
+	github.com/mattn/go-isatty v0.0.20
+	golang.org/x/sys v0.6.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/fabien-marty/tracerr v0.0.0-20240624051446-7f090eca46ee // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rivo/uniseg v0.2.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 require (
+	github.com/mattn/go-runewidth v0.0.16
 	github.com/stretchr/testify v1.9.0
 	github.com/vlad-tokarev/sloggcp v0.0.0-20230820053939-1b7dbb8c7b58
-	golang.org/x/sys v0.6.0 // indirect
 )
@@ -1,5 +1,7 @@
 package ansi
 
+import "fmt"
+
 const (
 	Reset         = "\033[0m"
 	Cyan          = "\033[36m"
@@ -11,4 +13,27 @@ const (
 	RedBackground = "\033[41m"
 	White         = "\033[37m"
 	Bold          = "\033[1m"
+	Dim           = "\033[2m"
+	Underline     = "\033[4m"
+	Reverse       = "\033[7m"
 )
+
+// Color256 returns the escape sequence of a foreground color of the 256-color palette.
+func Color256(n uint8) string {
+	return fmt.Sprintf("\033[38;5;%dm", n)
+}
+
+// BgColor256 returns the escape sequence of a background color of the 256-color palette.
+func BgColor256(n uint8) string {
+	return fmt.Sprintf("\033[48;5;%dm", n)
+}
+
+// RGB returns the escape sequence of a truecolor (24-bit) foreground color.
+func RGB(r, g, b uint8) string {
+	return fmt.Sprintf("\033[38;2;%d;%d;%dm", r, g, b)
+}
+
+// BgRGB returns the escape sequence of a truecolor (24-bit) background color.
+func BgRGB(r, g, b uint8) string {
+	return fmt.Sprintf("\033[48;2;%d;%d;%dm", r, g, b)
+}
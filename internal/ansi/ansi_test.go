@@ -0,0 +1,14 @@
+package ansi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColors(t *testing.T) {
+	assert.Equal(t, "\033[38;5;208m", Color256(208))
+	assert.Equal(t, "\033[48;5;196m", BgColor256(196))
+	assert.Equal(t, "\033[38;2;181;137;0m", RGB(0xb5, 0x89, 0x00))
+	assert.Equal(t, "\033[48;2;220;50;47m", BgRGB(0xdc, 0x32, 0x2f))
+}
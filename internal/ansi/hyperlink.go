@@ -0,0 +1,67 @@
+package ansi
+
+import (
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EditorURLTemplateDefault is the default editor URL template (to open source files in the default application).
+const EditorURLTemplateDefault = "file://{path}"
+
+// EditorURLTemplateVSCode is the editor URL template for Visual Studio Code.
+const EditorURLTemplateVSCode = "vscode://file{path}:{line}"
+
+// EditorURLTemplateIdea is the editor URL template for JetBrains IDEs.
+const EditorURLTemplateIdea = "idea://open?file={path}&line={line}"
+
+// Hyperlink returns the text as an OSC 8 terminal hyperlink to url.
+func Hyperlink(url string, text string) string {
+	return "\033]8;;" + url + "\033\\" + text + "\033]8;;\033\\"
+}
+
+// EditorURL returns the URL to open the given source file (absolute path) at the given line with the
+// given template ({path} and {line} are replaced, EditorURLTemplateDefault is used if the template is empty).
+func EditorURL(template string, path string, line int) string {
+	if template == "" {
+		template = EditorURLTemplateDefault
+	}
+	escapedPath := (&url.URL{Path: path}).EscapedPath()
+	return strings.NewReplacer("{path}", escapedPath, "{line}", strconv.Itoa(line)).Replace(template)
+}
+
+// IsURL returns true if s is an absolute http(s) URL.
+func IsURL(s string) bool {
+	if !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && u.Host != "" && !strings.ContainsAny(s, " \t\n\033")
+}
+
+// SupportsHyperlinks returns true if the terminal (guessed from environment variables) supports OSC 8 hyperlinks.
+//
+// The FORCE_HYPERLINK environment variable can be used to force the result ("0" or "false" => false, other values => true).
+func SupportsHyperlinks() bool {
+	if force := os.Getenv("FORCE_HYPERLINK"); force != "" {
+		return force != "0" && strings.ToLower(force) != "false"
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper":
+		return true
+	}
+	switch os.Getenv("TERM") {
+	case "xterm-kitty", "xterm-ghostty", "alacritty", "foot", "wezterm":
+		return true
+	}
+	for _, envVar := range []string{"WT_SESSION", "KONSOLE_VERSION", "DOMTERM"} {
+		if os.Getenv(envVar) != "" {
+			return true
+		}
+	}
+	if version, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && version >= 5000 {
+		return true
+	}
+	return false
+}
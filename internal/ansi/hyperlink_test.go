@@ -0,0 +1,40 @@
+package ansi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHyperlink(t *testing.T) {
+	assert.Equal(t, "\033]8;;https://example.com\033\\example\033]8;;\033\\", Hyperlink("https://example.com", "example"))
+}
+
+func TestEditorURL(t *testing.T) {
+	assert.Equal(t, "file:///home/foo/my%20project/main.go", EditorURL("", "/home/foo/my project/main.go", 12))
+	assert.Equal(t, "vscode://file/home/foo/main.go:12", EditorURL(EditorURLTemplateVSCode, "/home/foo/main.go", 12))
+	assert.Equal(t, "idea://open?file=/home/foo/main.go&line=12", EditorURL(EditorURLTemplateIdea, "/home/foo/main.go", 12))
+}
+
+func TestIsURL(t *testing.T) {
+	assert.True(t, IsURL("https://example.com/foo?bar=baz"))
+	assert.True(t, IsURL("http://localhost:8080"))
+	assert.False(t, IsURL("ftp://example.com"))
+	assert.False(t, IsURL("https://"))
+	assert.False(t, IsURL("https://example.com/foo bar"))
+	assert.False(t, IsURL("foo"))
+}
+
+func TestSupportsHyperlinks(t *testing.T) {
+	for _, envVar := range []string{"TERM_PROGRAM", "TERM", "WT_SESSION", "KONSOLE_VERSION", "DOMTERM", "VTE_VERSION"} {
+		t.Setenv(envVar, "")
+	}
+	t.Setenv("FORCE_HYPERLINK", "1")
+	assert.True(t, SupportsHyperlinks())
+	t.Setenv("FORCE_HYPERLINK", "0")
+	assert.False(t, SupportsHyperlinks())
+	t.Setenv("FORCE_HYPERLINK", "")
+	assert.False(t, SupportsHyperlinks())
+	t.Setenv("TERM_PROGRAM", "iTerm.app")
+	assert.True(t, SupportsHyperlinks())
+}
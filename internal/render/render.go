@@ -0,0 +1,29 @@
+package render
+
+import (
+	"io"
+	"log/slog"
+
+	"github.com/fabien-marty/slog-helpers/pkg/human"
+)
+
+// NewJSON returns a handler which writes records as JSON lines (like slog.JSONHandler with AddSource=true).
+//
+// The handler should be reused for all the records written to w (a dump or a subscriber for example).
+func NewJSON(w io.Writer) slog.Handler {
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		AddSource: true,
+	})
+}
+
+// NewHuman returns a handler which writes records as human readable lines (like human.Handler without colors
+// and with AddSource=true).
+//
+// The handler should be reused for all the records written to w (a dump or a subscriber for example).
+func NewHuman(w io.Writer) slog.Handler {
+	return human.New(w, &human.Options{
+		HandlerOptions: slog.HandlerOptions{
+			AddSource: true,
+		},
+	})
+}
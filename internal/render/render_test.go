@@ -0,0 +1,24 @@
+package render
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	record := slog.NewRecord(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), slog.LevelWarn, "hello", 0)
+	record.AddAttrs(slog.Group("group", slog.String("foo", "bar")))
+	buffer := &strings.Builder{}
+	assert.NoError(t, NewJSON(buffer).Handle(context.Background(), record))
+	assert.Equal(t, `{"time":"2024-01-02T03:04:05Z","level":"WARN","msg":"hello","group":{"foo":"bar"}}`+"\n", buffer.String())
+	buffer.Reset()
+	handler := NewHuman(buffer)
+	assert.NoError(t, handler.Handle(context.Background(), record))
+	assert.NoError(t, handler.Handle(context.Background(), record)) // reused
+	assert.Equal(t, strings.Repeat("2024-01-02T03:04:05Z [WARN ] hello {group.foo=bar}\n", 2), buffer.String())
+}
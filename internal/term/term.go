@@ -0,0 +1,39 @@
+package term
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// fder is implemented by *os.File (and by other writers backed by a file descriptor).
+type fder interface {
+	Fd() uintptr
+}
+
+// IsTerminal returns true if the given writer is a terminal.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(fder)
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// Width returns the width (in columns) of the terminal connected to the given writer.
+//
+// A positive integer in the COLUMNS environment variable overrides the detected width. ok is false if the writer
+// is not a terminal (or if its width can't be detected).
+func Width(w io.Writer) (width int, ok bool) {
+	if !IsTerminal(w) {
+		return 0, false
+	}
+	if columns, err := strconv.Atoi(strings.TrimSpace(os.Getenv("COLUMNS"))); err == nil && columns > 0 {
+		return columns, true
+	}
+	width = getWidth(w.(fder).Fd())
+	return width, width > 0
+}
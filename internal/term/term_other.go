@@ -0,0 +1,7 @@
+//go:build !unix && !windows
+
+package term
+
+func getWidth(fd uintptr) int {
+	return 0
+}
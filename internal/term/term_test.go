@@ -0,0 +1,22 @@
+package term
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWidthNotATerminal(t *testing.T) {
+	t.Setenv("COLUMNS", "100")
+	_, ok := Width(&bytes.Buffer{})
+	assert.False(t, ok)
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+	assert.False(t, IsTerminal(w))
+	_, ok = Width(w)
+	assert.False(t, ok)
+}
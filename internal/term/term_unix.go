@@ -0,0 +1,13 @@
+//go:build unix
+
+package term
+
+import "golang.org/x/sys/unix"
+
+func getWidth(fd uintptr) int {
+	ws, err := unix.IoctlGetWinsize(int(fd), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0
+	}
+	return int(ws.Col)
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package term
+
+import "golang.org/x/sys/windows"
+
+func getWidth(fd uintptr) int {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(fd), &info); err != nil {
+		return 0
+	}
+	return int(info.Window.Right-info.Window.Left) + 1
+}
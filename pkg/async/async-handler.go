@@ -0,0 +1,280 @@
+package async
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy is an enumeration type that defines what to do when the queue is full.
+type OverflowPolicy string
+
+// PolicyBlock is a policy that blocks the caller until there is room in the queue.
+const PolicyBlock OverflowPolicy = "block"
+
+// PolicyDropNewest is a policy that drops the new record when the queue is full.
+const PolicyDropNewest OverflowPolicy = "drop-newest"
+
+// PolicyDropOldest is a policy that drops the oldest queued record to make room for the new one.
+const PolicyDropOldest OverflowPolicy = "drop-oldest"
+
+// PolicyDefault is the default overflow policy.
+const PolicyDefault = PolicyBlock
+
+// QueueSizeDefault is the default size of the queue.
+const QueueSizeDefault = 1024
+
+var _ slog.Handler = &Handler{}
+
+// Options is a struct that contains the options for the (async) Handler.
+type Options struct {
+	QueueSize    int            // The maximum number of records waiting in the queue (default to QueueSizeDefault).
+	Policy       OverflowPolicy // What to do when the queue is full (default to PolicyDefault).
+	ErrorHandler func(error)    // If not nil, called (from the background goroutine) with errors returned by the decorated handler.
+}
+
+// Metrics is a snapshot of the queue metrics.
+type Metrics struct {
+	QueueDepth    int    // The number of records currently waiting in the queue.
+	QueueCapacity int    // The maximum number of records waiting in the queue.
+	Handled       uint64 // The number of records successfully handled by the decorated handler.
+	Dropped       uint64 // The number of records dropped because of the overflow policy.
+	Errors        uint64 // The number of records for which the decorated handler returned an error.
+}
+
+type item struct {
+	context context.Context
+	handler slog.Handler
+	record  slog.Record
+	seq     uint64
+}
+
+// queue is shared between a Handler and all the handlers derived from it (WithAttrs/WithGroup).
+//
+// Queued records get increasing sequence numbers (in the queue order) so that Flush can wait for the records
+// queued before the call only.
+type queue struct {
+	opts        *Options
+	items       chan item
+	done        chan struct{}
+	closeMutex  sync.RWMutex
+	closed      bool
+	pushMutex   sync.Mutex    // keeps the sequence numbers in the queue order
+	queued      atomic.Uint64 // sequence number of the last queued record
+	stateMutex  sync.Mutex
+	received    uint64        // sequence number of the last record received by the background goroutine
+	finished    uint64        // sequence number of the last record handled by the background goroutine
+	lastDropped uint64        // sequence number of the last record dropped from the head of the queue
+	changed     chan struct{} // if not nil, closed when the state changes (for Flush)
+	handled     atomic.Uint64
+	dropped     atomic.Uint64
+	errors      atomic.Uint64
+}
+
+// Handler is a slog handler that hands log records to a background goroutine through a bounded queue.
+type Handler struct {
+	slog.Handler
+	queue *queue
+}
+
+// New creates a new Handler and starts its background goroutine.
+//
+// Note: you should call Close (or at least Flush) before exiting the program to not lose queued records.
+func New(originalHandler slog.Handler, options *Options) *Handler {
+	if options.QueueSize <= 0 {
+		options.QueueSize = QueueSizeDefault
+	}
+	if options.Policy == "" {
+		options.Policy = PolicyDefault
+	}
+	q := &queue{
+		opts:  options,
+		items: make(chan item, options.QueueSize),
+		done:  make(chan struct{}),
+	}
+	go q.run()
+	return &Handler{
+		Handler: originalHandler,
+		queue:   q,
+	}
+}
+
+func (ah *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{
+		Handler: ah.Handler.WithGroup(name),
+		queue:   ah.queue,
+	}
+}
+
+func (ah *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{
+		Handler: ah.Handler.WithAttrs(attrs),
+		queue:   ah.queue,
+	}
+}
+
+// Handle queues the record for the background goroutine.
+//
+// If the Handler is closed, the record is handled synchronously by the decorated handler.
+func (ah *Handler) Handle(context context.Context, record slog.Record) error {
+	q := ah.queue
+	q.closeMutex.RLock()
+	defer q.closeMutex.RUnlock()
+	if q.closed {
+		return ah.Handler.Handle(context, record)
+	}
+	q.push(item{context: context, handler: ah.Handler, record: record.Clone()})
+	return nil
+}
+
+// Flush waits until all the records queued before the call are handled by the decorated handler (or dropped),
+// records queued during the call are not waited for.
+//
+// It returns the context error if the context is done before.
+func (ah *Handler) Flush(ctx context.Context) error {
+	q := ah.queue
+	target := q.queued.Load()
+	for {
+		q.stateMutex.Lock()
+		if q.flushed(target) {
+			q.stateMutex.Unlock()
+			return nil
+		}
+		if q.changed == nil {
+			q.changed = make(chan struct{})
+		}
+		changed := q.changed
+		q.stateMutex.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close flushes the queue and stops the background goroutine.
+//
+// Records handled after Close are handled synchronously by the decorated handler.
+// It returns the context error if the context is done before the queue is fully flushed.
+func (ah *Handler) Close(ctx context.Context) error {
+	q := ah.queue
+	q.closeMutex.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.items)
+	}
+	q.closeMutex.Unlock()
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Metrics returns a snapshot of the queue metrics.
+func (ah *Handler) Metrics() Metrics {
+	q := ah.queue
+	return Metrics{
+		QueueDepth:    len(q.items),
+		QueueCapacity: cap(q.items),
+		Handled:       q.handled.Load(),
+		Dropped:       q.dropped.Load(),
+		Errors:        q.errors.Load(),
+	}
+}
+
+// flushed returns true if all the records with a sequence number lower or equal to target are handled or dropped.
+//
+// The stateMutex must be held.
+func (q *queue) flushed(target uint64) bool {
+	switch {
+	case q.finished >= target:
+		return true
+	case q.received > q.finished:
+		// the record being handled is the only received one which is not finished
+		return q.received > target
+	default:
+		// nothing is being handled, the records after finished were dropped (if target is not after the last drop)
+		return q.lastDropped >= target
+	}
+}
+
+// notify wakes up the Flush calls waiting for a state change.
+//
+// The stateMutex must be held.
+func (q *queue) notify() {
+	if q.changed != nil {
+		close(q.changed)
+		q.changed = nil
+	}
+}
+
+// drop records that the record with the given sequence number was dropped from the head of the queue.
+func (q *queue) drop(seq uint64) {
+	q.dropped.Add(1)
+	q.stateMutex.Lock()
+	defer q.stateMutex.Unlock()
+	if seq-1 > q.lastDropped {
+		// the previous record was not dropped, so it was received by the background goroutine
+		q.received = max(q.received, seq-1)
+	}
+	q.lastDropped = seq
+	q.notify()
+}
+
+func (q *queue) push(it item) {
+	q.pushMutex.Lock()
+	defer q.pushMutex.Unlock()
+	it.seq = q.queued.Load() + 1
+	switch q.opts.Policy {
+	case PolicyDropNewest:
+		select {
+		case q.items <- it:
+		default:
+			q.dropped.Add(1)
+			return
+		}
+	case PolicyDropOldest:
+		for {
+			select {
+			case q.items <- it:
+				q.queued.Store(it.seq)
+				return
+			default:
+			}
+			select {
+			case old := <-q.items:
+				q.drop(old.seq)
+			default:
+			}
+		}
+	default:
+		q.items <- it
+	}
+	q.queued.Store(it.seq)
+}
+
+func (q *queue) run() {
+	defer close(q.done)
+	for it := range q.items {
+		q.stateMutex.Lock()
+		q.received = max(q.received, it.seq)
+		q.stateMutex.Unlock()
+		err := it.handler.Handle(it.context, it.record)
+		if err != nil {
+			q.errors.Add(1)
+			if q.opts.ErrorHandler != nil {
+				q.opts.ErrorHandler(err)
+			}
+		} else {
+			q.handled.Add(1)
+		}
+		q.stateMutex.Lock()
+		q.finished = it.seq
+		q.notify()
+		q.stateMutex.Unlock()
+	}
+}
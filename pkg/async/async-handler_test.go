@@ -0,0 +1,187 @@
+package async
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fabien-marty/slog-helpers/pkg/external"
+	"github.com/stretchr/testify/assert"
+)
+
+type collector struct {
+	mutex    sync.Mutex
+	messages []string
+	blocker  chan struct{}
+	delay    time.Duration
+}
+
+func (c *collector) callback(_ time.Time, level slog.Level, message string, attrs []external.StringifiedAttr) error {
+	if c.blocker != nil {
+		<-c.blocker
+	}
+	time.Sleep(c.delay)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, attr := range attrs {
+		message += " " + attr.String()
+	}
+	c.messages = append(c.messages, message)
+	return nil
+}
+
+func (c *collector) get() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]string{}, c.messages...)
+}
+
+func newCollectorHandler(c *collector) slog.Handler {
+	return external.New(&external.Options{
+		StringifiedCallback: c.callback,
+	})
+}
+
+func TestAsyncHandlerFlush(t *testing.T) {
+	c := &collector{}
+	h := New(newCollectorHandler(c), &Options{})
+	logger := slog.New(h)
+	for i := 0; i < 100; i++ {
+		logger.Info("hello", slog.Int("i", i))
+	}
+	logger.With(slog.String("foo", "bar")).Warn("last")
+	err := h.Flush(context.Background())
+	assert.NoError(t, err)
+	messages := c.get()
+	assert.Equal(t, 101, len(messages))
+	assert.Equal(t, "hello i=0", messages[0])
+	assert.Equal(t, "hello i=99", messages[99])
+	assert.Equal(t, "last foo=bar", messages[100])
+	metrics := h.Metrics()
+	assert.Equal(t, uint64(101), metrics.Handled)
+	assert.Equal(t, uint64(0), metrics.Dropped)
+	assert.Equal(t, 0, metrics.QueueDepth)
+	assert.Equal(t, QueueSizeDefault, metrics.QueueCapacity)
+	assert.NoError(t, h.Close(context.Background()))
+}
+
+func TestAsyncHandlerDropNewest(t *testing.T) {
+	c := &collector{blocker: make(chan struct{})}
+	h := New(newCollectorHandler(c), &Options{
+		QueueSize: 2,
+		Policy:    PolicyDropNewest,
+	})
+	logger := slog.New(h)
+	logger.Info("first") // taken by the background goroutine (blocked)
+	assert.Eventually(t, func() bool { return h.Metrics().QueueDepth == 0 }, time.Second, time.Millisecond)
+	logger.Info("second")
+	logger.Info("third")
+	logger.Info("fourth") // dropped
+	assert.Equal(t, uint64(1), h.Metrics().Dropped)
+	assert.Equal(t, 2, h.Metrics().QueueDepth)
+	close(c.blocker)
+	assert.NoError(t, h.Close(context.Background()))
+	assert.Equal(t, []string{"first", "second", "third"}, c.get())
+}
+
+func TestAsyncHandlerDropOldest(t *testing.T) {
+	c := &collector{blocker: make(chan struct{})}
+	h := New(newCollectorHandler(c), &Options{
+		QueueSize: 2,
+		Policy:    PolicyDropOldest,
+	})
+	logger := slog.New(h)
+	logger.Info("first") // taken by the background goroutine (blocked)
+	assert.Eventually(t, func() bool { return h.Metrics().QueueDepth == 0 }, time.Second, time.Millisecond)
+	logger.Info("second") // dropped
+	logger.Info("third")
+	logger.Info("fourth")
+	assert.Equal(t, uint64(1), h.Metrics().Dropped)
+	close(c.blocker)
+	assert.NoError(t, h.Close(context.Background()))
+	assert.Equal(t, []string{"first", "third", "fourth"}, c.get())
+}
+
+func TestAsyncHandlerFlushTimeout(t *testing.T) {
+	c := &collector{blocker: make(chan struct{})}
+	h := New(newCollectorHandler(c), &Options{})
+	slog.New(h).Info("blocked")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, h.Flush(ctx), context.DeadlineExceeded)
+	close(c.blocker)
+	assert.NoError(t, h.Flush(context.Background()))
+	assert.NoError(t, h.Close(context.Background()))
+}
+
+func TestAsyncHandlerFlushUnderLoad(t *testing.T) {
+	c := &collector{delay: time.Millisecond}
+	h := New(newCollectorHandler(c), &Options{QueueSize: 100})
+	logger := slog.New(h)
+	stop := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					logger.Info("noise")
+				}
+			}
+		}()
+	}
+	defer func() {
+		close(stop)
+		wg.Wait()
+		assert.NoError(t, h.Close(context.Background()))
+	}()
+	logger.Info("before flush")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	assert.NoError(t, h.Flush(ctx)) // the records queued during the call are not waited for
+	assert.Contains(t, c.get(), "before flush")
+}
+
+func TestAsyncHandlerFlushDropOldest(t *testing.T) {
+	c := &collector{blocker: make(chan struct{})}
+	h := New(newCollectorHandler(c), &Options{
+		QueueSize: 1,
+		Policy:    PolicyDropOldest,
+	})
+	logger := slog.New(h)
+	logger.Info("first") // taken by the background goroutine (blocked)
+	assert.Eventually(t, func() bool { return h.Metrics().QueueDepth == 0 }, time.Second, time.Millisecond)
+	logger.Info("second") // dropped
+	logger.Info("third")  // dropped
+	logger.Info("fourth")
+	flushed := make(chan error)
+	go func() {
+		flushed <- h.Flush(context.Background())
+	}()
+	select {
+	case <-flushed:
+		assert.Fail(t, "flushed before the blocked record")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(c.blocker)
+	assert.NoError(t, <-flushed)
+	assert.Equal(t, []string{"first", "fourth"}, c.get())
+	assert.NoError(t, h.Close(context.Background()))
+}
+
+func TestAsyncHandlerAfterClose(t *testing.T) {
+	c := &collector{}
+	h := New(newCollectorHandler(c), &Options{})
+	logger := slog.New(h)
+	logger.Info("before")
+	assert.NoError(t, h.Close(context.Background()))
+	assert.NoError(t, h.Close(context.Background())) // idempotent
+	logger.Info("after")                             // handled synchronously
+	assert.Equal(t, []string{"before", "after"}, c.get())
+}
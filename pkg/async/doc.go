@@ -0,0 +1,24 @@
+// async.Handler is a slog handler that hands log records to a background goroutine through a bounded queue.
+//
+// It does not output the log record itself, it decorates another slog.Handler given in the New() method.
+// The decorated handler is called from a single background goroutine (in the order records were queued),
+// so the caller of a logging method only pays for the queueing.
+//
+// When the queue is full, the OverflowPolicy decides what to do:
+//   - PolicyBlock: the caller waits until there is room in the queue (no record is lost)
+//   - PolicyDropNewest: the new record is dropped
+//   - PolicyDropOldest: the oldest queued record is dropped to make room for the new one
+//
+// Records still in the queue are lost if the program exits without calling Flush or Close.
+//
+// Full example:
+//
+//	handler := slog.NewJSONHandler(os.Stderr, nil)
+//	asyncHandler := New(handler, &Options{
+//		QueueSize: 4096,
+//		Policy:    PolicyDropOldest,
+//	})
+//	defer asyncHandler.Close(context.Background())
+//	logger := slog.New(asyncHandler)
+//	logger.Info("this record is written by a background goroutine")
+package async
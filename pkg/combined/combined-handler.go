@@ -0,0 +1,252 @@
+package combined
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fabien-marty/slog-helpers/internal/accumulator"
+	"github.com/fabien-marty/slog-helpers/internal/bufferpool"
+)
+
+// Keys of the well-known HTTP attributes (in the KeyGroup group).
+const (
+	KeyGroup      = "http"
+	KeyMethod     = "method"
+	KeyPath       = "path"
+	KeyQuery      = "query"
+	KeyProto      = "proto"
+	KeyStatus     = "status"
+	KeyBytes      = "bytes"
+	KeyRemoteAddr = "remote_addr"
+	KeyUser       = "user"
+	KeyUserAgent  = "user_agent"
+	KeyReferer    = "referer"
+)
+
+// TimeFormat is the time format of the Common/Combined Log Format.
+const TimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+var _ slog.Handler = &Handler{}
+
+// Options is a struct that contains the options for the (combined) Handler.
+type Options struct {
+	slog.HandlerOptions
+	Common bool // If true, the Common Log Format is used (without referer and user agent).
+}
+
+type lockedWriter struct {
+	mutex sync.Mutex
+	w     io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mutex.Lock()
+	defer lw.mutex.Unlock()
+	return lw.w.Write(p)
+}
+
+// Handler is a slog handler that renders HTTP access records in Combined Log Format
+// (and other records with a slog.TextHandler).
+type Handler struct {
+	slog.Handler // the fallback text handler
+	*accumulator.Accumulator
+	opts *Options
+	w    *lockedWriter
+}
+
+// New creates a new Handler.
+func New(w io.Writer, options *Options) *Handler {
+	lw := &lockedWriter{w: w}
+	return &Handler{
+		Handler:     slog.NewTextHandler(lw, &options.HandlerOptions),
+		Accumulator: accumulator.New(),
+		opts:        options,
+		w:           lw,
+	}
+}
+
+func (ch *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{
+		Handler:     ch.Handler.WithGroup(name),
+		Accumulator: ch.Accumulator.WithGroup(name),
+		opts:        ch.opts,
+		w:           ch.w,
+	}
+}
+
+func (ch *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{
+		Handler:     ch.Handler.WithAttrs(attrs),
+		Accumulator: ch.Accumulator.WithAttrs(attrs),
+		opts:        ch.opts,
+		w:           ch.w,
+	}
+}
+
+// access contains the well-known HTTP attributes of a record.
+type access struct {
+	fields    map[string]string
+	remaining []slog.Attr // the other attributes of the http group
+}
+
+func (ch *Handler) consumed(key string) bool {
+	switch key {
+	case KeyMethod, KeyPath, KeyQuery, KeyProto, KeyStatus, KeyBytes, KeyRemoteAddr, KeyUser:
+		return true
+	case KeyUserAgent, KeyReferer:
+		return !ch.opts.Common
+	}
+	return false
+}
+
+// getAccess returns the well-known HTTP attributes of the group attr (nil if attr is not an access group).
+func (ch *Handler) getAccess(attr slog.Attr) *access {
+	if attr.Key != KeyGroup || attr.Value.Kind() != slog.KindGroup {
+		return nil
+	}
+	a := &access{fields: map[string]string{}}
+	for _, child := range attr.Value.Group() {
+		if child.Value.Kind() != slog.KindGroup && ch.consumed(child.Key) {
+			a.fields[child.Key] = child.Value.Resolve().String()
+		} else {
+			a.remaining = append(a.remaining, child)
+		}
+	}
+	if a.fields[KeyMethod] == "" || a.fields[KeyStatus] == "" {
+		return nil
+	}
+	return a
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func quote(s string) string {
+	return strconv.Quote(dashIfEmpty(s))
+}
+
+// token returns s as a single space-free token of the log line: as is if it only contains printable
+// ASCII characters (without space and double quote), quoted and escaped otherwise.
+func token(s string) string {
+	for _, c := range []byte(s) {
+		if c <= ' ' || c >= 0x7f || c == '"' {
+			return strconv.Quote(s)
+		}
+	}
+	return dashIfEmpty(s)
+}
+
+// findAccess looks for the access group in attrs (at the top level first, then in nested groups). It returns
+// the well-known HTTP attributes and attrs without them (nil if there is no access group).
+func (ch *Handler) findAccess(attrs []slog.Attr) (*access, []slog.Attr) {
+	for i, attr := range attrs {
+		if a := ch.getAccess(attr); a != nil {
+			remaining := append([]slog.Attr{}, attrs[:i]...)
+			if len(a.remaining) > 0 {
+				remaining = append(remaining, slog.Attr{Key: KeyGroup, Value: slog.GroupValue(a.remaining...)})
+			}
+			return a, append(remaining, attrs[i+1:]...)
+		}
+	}
+	for i, attr := range attrs {
+		if attr.Value.Kind() != slog.KindGroup {
+			continue
+		}
+		a, children := ch.findAccess(attr.Value.Group())
+		if a == nil {
+			continue
+		}
+		remaining := append([]slog.Attr{}, attrs[:i]...)
+		if len(children) > 0 {
+			remaining = append(remaining, slog.Attr{Key: attr.Key, Value: slog.GroupValue(children...)})
+		}
+		return a, append(remaining, attrs[i+1:]...)
+	}
+	return nil, nil
+}
+
+// Handle renders the record in Combined Log Format if it is an access record (or falls back to the text rendering).
+func (ch *Handler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := ch.Accumulator.AssembleWithRecordAttrs(record)
+	a, remaining := ch.findAccess(attrs)
+	if a == nil {
+		return ch.Handler.Handle(ctx, record)
+	}
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	remoteAddr := a.fields[KeyRemoteAddr]
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		remoteAddr = host
+	}
+	requestLine := a.fields[KeyMethod] + " " + a.fields[KeyPath]
+	if a.fields[KeyQuery] != "" {
+		requestLine += "?" + a.fields[KeyQuery]
+	}
+	if a.fields[KeyProto] != "" {
+		requestLine += " " + a.fields[KeyProto]
+	}
+	bytes := a.fields[KeyBytes]
+	if bytes == "0" {
+		bytes = "-"
+	}
+	buffer.WriteString(token(remoteAddr))
+	buffer.WriteString(" - ")
+	buffer.WriteString(token(a.fields[KeyUser]))
+	buffer.WriteString(" [")
+	buffer.WriteString(record.Time.Format(TimeFormat))
+	buffer.WriteString("] ")
+	buffer.WriteString(strconv.Quote(requestLine))
+	buffer.WriteString(" ")
+	buffer.WriteString(a.fields[KeyStatus])
+	buffer.WriteString(" ")
+	buffer.WriteString(dashIfEmpty(bytes))
+	if !ch.opts.Common {
+		buffer.WriteString(" ")
+		buffer.WriteString(quote(a.fields[KeyReferer]))
+		buffer.WriteString(" ")
+		buffer.WriteString(quote(a.fields[KeyUserAgent]))
+	}
+	logfmt, err := ch.logfmt(ctx, record, remaining)
+	if err != nil {
+		return err
+	}
+	if logfmt != "" {
+		buffer.WriteString(" ")
+		buffer.WriteString(logfmt)
+	}
+	buffer.WriteString("\n")
+	_, err = ch.w.Write(buffer.Bytes())
+	return err
+}
+
+// logfmt renders the given attributes (and the source if AddSource is set) in logfmt.
+func (ch *Handler) logfmt(ctx context.Context, record slog.Record, attrs []slog.Attr) (string, error) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	handler := slog.NewTextHandler(buffer, &slog.HandlerOptions{
+		AddSource: ch.opts.AddSource,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if len(groups) == 0 && (attr.Key == slog.LevelKey || attr.Key == slog.MessageKey) {
+				return slog.Attr{}
+			}
+			if ch.opts.ReplaceAttr != nil {
+				return ch.opts.ReplaceAttr(groups, attr)
+			}
+			return attr
+		},
+	})
+	r := slog.NewRecord(time.Time{}, record.Level, "", record.PC) // zero time => not rendered
+	r.AddAttrs(attrs...)
+	err := handler.Handle(ctx, r)
+	return strings.TrimSuffix(buffer.String(), "\n"), err
+}
@@ -0,0 +1,102 @@
+package combined
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func accessGroup() slog.Attr {
+	return slog.Group(KeyGroup,
+		slog.String(KeyMethod, "GET"),
+		slog.String(KeyPath, "/foo"),
+		slog.String(KeyQuery, "bar=baz"),
+		slog.String(KeyProto, "HTTP/1.1"),
+		slog.Int(KeyStatus, 200),
+		slog.Int(KeyBytes, 1234),
+		slog.Duration("duration", 3*time.Millisecond),
+		slog.String(KeyRemoteAddr, "127.0.0.1:51234"),
+		slog.String(KeyUserAgent, "curl/8.0 \"quoted\""),
+	)
+}
+
+func TestCombinedHandler(t *testing.T) {
+	buffer := &strings.Builder{}
+	logger := slog.New(New(buffer, &Options{})).With(slog.String("request_id", "1234"))
+	logger.Info("http request", accessGroup(), slog.String("foo", "bar baz"))
+	logger.Info("not an access record", slog.String("foo", "bar"))
+	lines := strings.Split(buffer.String(), "\n")
+	assert.Len(t, lines, 3)
+	prefix, suffix, found := strings.Cut(lines[0], "[")
+	assert.True(t, found)
+	assert.Equal(t, "127.0.0.1 - - ", prefix)
+	_, suffix, found = strings.Cut(suffix, "] ")
+	assert.True(t, found)
+	assert.Equal(t, `"GET /foo?bar=baz HTTP/1.1" 200 1234 "-" "curl/8.0 \"quoted\"" request_id=1234 http.duration=3ms foo="bar baz"`, suffix)
+	assert.True(t, strings.HasPrefix(lines[1], "time="))
+	assert.True(t, strings.HasSuffix(lines[1], ` level=INFO msg="not an access record" request_id=1234 foo=bar`))
+}
+
+func TestCombinedHandlerCommon(t *testing.T) {
+	buffer := &strings.Builder{}
+	logger := slog.New(New(buffer, &Options{Common: true}))
+	logger.Info("http request", slog.Group(KeyGroup,
+		slog.String(KeyMethod, "POST"),
+		slog.String(KeyPath, "/foo"),
+		slog.Int(KeyStatus, 204),
+		slog.Int(KeyBytes, 0),
+		slog.String(KeyUserAgent, "curl/8.0"),
+	))
+	_, suffix, found := strings.Cut(buffer.String(), "] ")
+	assert.True(t, found)
+	assert.Equal(t, `"POST /foo" 204 - http.user_agent=curl/8.0`+"\n", suffix)
+}
+
+func TestCombinedHandlerTime(t *testing.T) {
+	buffer := &strings.Builder{}
+	h := New(buffer, &Options{})
+	record := slog.NewRecord(time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)), slog.LevelInfo, "http request", 0)
+	record.AddAttrs(slog.Group(KeyGroup, slog.String(KeyMethod, "GET"), slog.String(KeyPath, "/"), slog.Int(KeyStatus, 200)))
+	assert.NoError(t, h.Handle(context.Background(), record))
+	assert.Equal(t, `- - - [10/Oct/2000:13:55:36 -0700] "GET /" 200 - "-" "-"`+"\n", buffer.String())
+}
+
+func TestCombinedHandlerNestedGroup(t *testing.T) {
+	buffer := &strings.Builder{}
+	logger := slog.New(New(buffer, &Options{Common: true})).WithGroup("app").With(slog.String("foo", "bar"))
+	logger.Info("http request", slog.Group(KeyGroup,
+		slog.String(KeyMethod, "GET"),
+		slog.String(KeyPath, "/"),
+		slog.Int(KeyStatus, 200),
+		slog.String("extra", "x"),
+	))
+	_, suffix, found := strings.Cut(buffer.String(), "] ")
+	assert.True(t, found)
+	assert.Equal(t, `"GET /" 200 - app.foo=bar app.http.extra=x`+"\n", suffix)
+}
+
+func TestCombinedHandlerUser(t *testing.T) {
+	for user, expected := range map[string]string{
+		"":             "-",
+		"alice":        "alice",
+		"bob smith":    `"bob smith"`,
+		"eve\n1.2.3.4": `"eve\n1.2.3.4"`,
+		`"quoted"`:     `"\"quoted\""`,
+	} {
+		buffer := &strings.Builder{}
+		logger := slog.New(New(buffer, &Options{Common: true}))
+		logger.Info("http request", slog.Group(KeyGroup,
+			slog.String(KeyMethod, "GET"),
+			slog.String(KeyPath, "/"),
+			slog.Int(KeyStatus, 200),
+			slog.String(KeyUser, user),
+		))
+		prefix, _, found := strings.Cut(buffer.String(), " [")
+		assert.True(t, found)
+		assert.Equal(t, "- - "+expected, prefix)
+	}
+}
@@ -0,0 +1,26 @@
+// combined.Handler is a slog handler that renders HTTP access records in NCSA Combined (or Common) Log Format.
+//
+// A record is an access record if it has an "http" group (at the top level or nested in other groups, for example
+// with a logger.WithGroup() call) with (at least) "method" and "status" attributes (this is the case of the records
+// logged by slogc.HTTPMiddleware). The remote host and user fields are quoted (and escaped) if they contain spaces,
+// double quotes or non printable characters. The well-known attributes of the group are used to
+// build the Combined Log Format line, the remaining attributes (of the group and of the record) are appended in logfmt.
+//
+// Other records fall back to the standard text rendering (slog.TextHandler).
+//
+// Full example:
+//
+//	logger := slog.New(New(os.Stdout, &Options{}))
+//	logger.Info("http request", slog.Group("http",
+//		slog.String("method", "GET"),
+//		slog.String("path", "/foo"),
+//		slog.String("proto", "HTTP/1.1"),
+//		slog.Int("status", 200),
+//		slog.Int("bytes", 1234),
+//		slog.String("remote_addr", "127.0.0.1:51234"),
+//		slog.String("user_agent", "curl/8.0"),
+//		slog.Duration("duration", 3*time.Millisecond),
+//	), slog.String("request_id", "1234"))
+//	// => 127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /foo HTTP/1.1" 200 1234 "-" "curl/8.0" http.duration=3ms request_id=1234
+//	logger.Info("hello") // => time=2024-10-10T13:55:36.000Z level=INFO msg=hello
+package combined
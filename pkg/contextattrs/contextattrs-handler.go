@@ -0,0 +1,228 @@
+package contextattrs
+
+import (
+	"container/list"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Extractor is a function that returns attributes from a context (it can return nil).
+type Extractor func(ctx context.Context) []slog.Attr
+
+// Registry is a concurrency safe list of extractors.
+type Registry struct {
+	mutex      sync.RWMutex
+	extractors []Extractor
+}
+
+// NewRegistry creates a new Registry with the given extractors.
+func NewRegistry(extractors ...Extractor) *Registry {
+	return &Registry{
+		extractors: extractors,
+	}
+}
+
+// Register adds an extractor to the registry.
+func (r *Registry) Register(extractor Extractor) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.extractors = append(r.extractors, extractor)
+}
+
+// Extract calls all the extractors of the registry (in the registration order) and returns the extracted attributes.
+func (r *Registry) Extract(ctx context.Context) []slog.Attr {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return extract(ctx, nil, r.extractors)
+}
+
+func extract(ctx context.Context, attrs []slog.Attr, extractors []Extractor) []slog.Attr {
+	for _, extractor := range extractors {
+		attrs = append(attrs, extractor(ctx)...)
+	}
+	return attrs
+}
+
+// DefaultRegistry is the registry used by handlers without explicit Registry in their Options.
+var DefaultRegistry = NewRegistry()
+
+// Register adds an extractor to the DefaultRegistry.
+func Register(extractor Extractor) {
+	DefaultRegistry.Register(extractor)
+}
+
+var _ slog.Handler = &Handler{}
+
+// Options is a struct that contains the options for the (context attributes) Handler.
+type Options struct {
+	Registry   *Registry   // The registry of extractors (default to DefaultRegistry).
+	Extractors []Extractor // Some additional extractors (called after the ones of the registry).
+}
+
+// op is a WithAttrs (group == "") or a WithGroup call.
+type op struct {
+	group string
+	attrs []slog.Attr
+}
+
+// rebuiltCacheSize is the maximum number of rebuilt handlers kept by a Handler with groups
+// (for example one per request in progress).
+const rebuiltCacheSize = 128
+
+// rebuilt is a handler rebuilt with some extracted attributes at the top level (see Handler.Handle).
+type rebuilt struct {
+	key     string // serialized extracted attributes
+	handler slog.Handler
+}
+
+// rebuiltCache is a LRU cache of rebuilt handlers (keyed by the serialized extracted attributes).
+type rebuiltCache struct {
+	mutex   sync.Mutex
+	entries map[string]*list.Element // values of the elements are *rebuilt
+	order   list.List                // the most recently used first
+}
+
+// get returns the rebuilt handler for the given key (or nil).
+func (c *rebuiltCache) get(key string) slog.Handler {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*rebuilt).handler
+}
+
+// put adds a rebuilt handler to the cache (and evicts the least recently used one if the cache is full).
+func (c *rebuiltCache) put(key string, handler slog.Handler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	if c.entries == nil {
+		c.entries = map[string]*list.Element{}
+	}
+	if c.order.Len() >= rebuiltCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*rebuilt).key)
+	}
+	c.entries[key] = c.order.PushFront(&rebuilt{key: key, handler: handler})
+}
+
+// Handler is a slog handler that adds attributes extracted from the context to every record.
+type Handler struct {
+	slog.Handler
+	root      slog.Handler // the original handler (without WithAttrs/WithGroup calls)
+	ops       []op         // WithAttrs/WithGroup calls since root
+	hasGroups bool
+	opts      *Options
+	cache     *rebuiltCache // the rebuilt handlers (only if hasGroups)
+}
+
+// New creates a new Handler.
+func New(originalHandler slog.Handler, options *Options) *Handler {
+	if options.Registry == nil {
+		options.Registry = DefaultRegistry
+	}
+	return &Handler{
+		Handler: originalHandler,
+		root:    originalHandler,
+		opts:    options,
+	}
+}
+
+func (ch *Handler) withOp(handler slog.Handler, o op) *Handler {
+	ops := make([]op, len(ch.ops), len(ch.ops)+1)
+	copy(ops, ch.ops)
+	return &Handler{
+		Handler:   handler,
+		root:      ch.root,
+		ops:       append(ops, o),
+		hasGroups: ch.hasGroups || o.group != "",
+		opts:      ch.opts,
+		cache:     &rebuiltCache{},
+	}
+}
+
+func (ch *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return ch
+	}
+	return ch.withOp(ch.Handler.WithGroup(name), op{group: name})
+}
+
+func (ch *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return ch.withOp(ch.Handler.WithAttrs(attrs), op{attrs: attrs})
+}
+
+// Handle adds the attributes extracted from the context to the record and forwards it to the original handler.
+func (ch *Handler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := ch.opts.Registry.Extract(ctx)
+	attrs = extract(ctx, attrs, ch.opts.Extractors)
+	if len(attrs) == 0 {
+		return ch.Handler.Handle(ctx, record)
+	}
+	if !ch.hasGroups {
+		record.AddAttrs(attrs...)
+		return ch.Handler.Handle(ctx, record)
+	}
+	// the logger has groups => we rebuild the handler with the extracted attributes at the top level
+	// (rebuilt handlers are cached by extracted attributes, for example for all the records of a request)
+	key, cacheable := cacheKey(attrs)
+	if cacheable {
+		if handler := ch.cache.get(key); handler != nil {
+			return handler.Handle(ctx, record)
+		}
+	}
+	handler := ch.root.WithAttrs(attrs)
+	for _, o := range ch.ops {
+		if o.group != "" {
+			handler = handler.WithGroup(o.group)
+		} else {
+			handler = handler.WithAttrs(o.attrs)
+		}
+	}
+	if cacheable {
+		ch.cache.put(key, handler)
+	}
+	return handler.Handle(ctx, record)
+}
+
+// cacheKey serializes the given attributes. It returns false if they can't be compared by their
+// serialization (values of kind Any or LogValuer).
+func cacheKey(attrs []slog.Attr) (string, bool) {
+	var sb strings.Builder
+	if !writeCacheKey(&sb, attrs) {
+		return "", false
+	}
+	return sb.String(), true
+}
+
+func writeCacheKey(sb *strings.Builder, attrs []slog.Attr) bool {
+	for _, attr := range attrs {
+		sb.WriteString(attr.Key)
+		sb.WriteByte(0)
+		switch attr.Value.Kind() {
+		case slog.KindAny, slog.KindLogValuer:
+			return false
+		case slog.KindGroup:
+			sb.WriteByte('{')
+			if !writeCacheKey(sb, attr.Value.Group()) {
+				return false
+			}
+			sb.WriteByte('}')
+		default:
+			sb.WriteString(attr.Value.Kind().String())
+			sb.WriteByte(0)
+			sb.WriteString(attr.Value.String())
+		}
+		sb.WriteByte(0)
+	}
+	return true
+}
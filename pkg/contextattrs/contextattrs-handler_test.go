@@ -0,0 +1,92 @@
+package contextattrs
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type requestIDKey struct{}
+
+func requestIDExtractor(ctx context.Context) []slog.Attr {
+	if requestID, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return []slog.Attr{slog.String("request_id", requestID)}
+	}
+	return nil
+}
+
+func newTextHandler(buffer *strings.Builder) slog.Handler {
+	return slog.NewTextHandler(buffer, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+}
+
+func TestContextAttrsHandler(t *testing.T) {
+	buffer := &strings.Builder{}
+	h := New(newTextHandler(buffer), &Options{
+		Registry:   NewRegistry(requestIDExtractor),
+		Extractors: []Extractor{func(ctx context.Context) []slog.Attr { return []slog.Attr{slog.String("tenant", "acme")} }},
+	})
+	logger := slog.New(h).With(slog.String("foo", "bar"))
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "1234")
+	logger.InfoContext(ctx, "hello")
+	logger.WithGroup("group").InfoContext(ctx, "grouped", slog.String("key", "value"))
+	logger.Info("no context")
+	assert.Equal(t, strings.Join([]string{
+		"level=INFO msg=hello foo=bar request_id=1234 tenant=acme",
+		"level=INFO msg=grouped request_id=1234 tenant=acme foo=bar group.key=value",
+		"level=INFO msg=\"no context\" foo=bar tenant=acme",
+		"",
+	}, "\n"), buffer.String())
+}
+
+func TestContextAttrsHandlerDefaultRegistry(t *testing.T) {
+	previous := DefaultRegistry
+	defer func() { DefaultRegistry = previous }()
+	DefaultRegistry = NewRegistry()
+	buffer := &strings.Builder{}
+	logger := slog.New(New(newTextHandler(buffer), &Options{}))
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "1234")
+	logger.InfoContext(ctx, "before")
+	Register(requestIDExtractor)
+	logger.InfoContext(ctx, "after")
+	assert.Equal(t, "level=INFO msg=before\nlevel=INFO msg=after request_id=1234\n", buffer.String())
+}
+
+func TestContextAttrsHandlerRebuiltCache(t *testing.T) {
+	buffer := &strings.Builder{}
+	h := New(newTextHandler(buffer), &Options{Registry: NewRegistry(requestIDExtractor)})
+	grouped := slog.New(h).WithGroup("group")
+	cache := grouped.Handler().(*Handler).cache
+	key1, _ := cacheKey([]slog.Attr{slog.String("request_id", "1")})
+	ctx1 := context.WithValue(context.Background(), requestIDKey{}, "1")
+	ctx2 := context.WithValue(context.Background(), requestIDKey{}, "2")
+	grouped.InfoContext(ctx1, "a")
+	rebuilt1 := cache.get(key1)
+	assert.NotNil(t, rebuilt1)
+	grouped.InfoContext(ctx2, "b")
+	grouped.InfoContext(ctx1, "c")
+	assert.Same(t, rebuilt1, cache.get(key1)) // reused (interleaved requests)
+	assert.Equal(t, 2, cache.order.Len())
+	assert.Equal(t, strings.Join([]string{
+		"level=INFO msg=a request_id=1",
+		"level=INFO msg=b request_id=2",
+		"level=INFO msg=c request_id=1",
+		"",
+	}, "\n"), buffer.String())
+	for i := 0; i < 2*rebuiltCacheSize; i++ {
+		grouped.InfoContext(context.WithValue(context.Background(), requestIDKey{}, strconv.Itoa(i)), "d")
+	}
+	assert.Equal(t, rebuiltCacheSize, cache.order.Len())
+	assert.Equal(t, rebuiltCacheSize, len(cache.entries))
+	assert.Nil(t, cache.get(key1)) // evicted
+}
@@ -0,0 +1,27 @@
+// contextattrs.Handler is a slog handler that adds attributes extracted from the context to every record.
+//
+// It does not output the log record itself, it decorates another slog.Handler given in the New() method.
+//
+// Extractors are simple functions returning attributes from a context.Context (for example a request ID,
+// a user ID or a tenant ID stored in the context by a middleware). They are registered in a Registry
+// (DefaultRegistry by default) or given directly in the Options.
+//
+// Extracted attributes are added at the top level of the record (even if the logger has groups) and only for
+// records logged with a context (InfoContext, ErrorContext...).
+// If the logger has groups, the handler chain is rebuilt with the extracted attributes at the top level
+// (the rebuilt chains of the last extracted attributes, for example of the requests in progress, are cached and reused).
+//
+// Full example:
+//
+//	type requestIDKey struct{}
+//
+//	Register(func(ctx context.Context) []slog.Attr {
+//		if requestID, ok := ctx.Value(requestIDKey{}).(string); ok {
+//			return []slog.Attr{slog.String("request_id", requestID)}
+//		}
+//		return nil
+//	})
+//	logger := slog.New(New(slog.NewJSONHandler(os.Stderr, nil), &Options{}))
+//	ctx := context.WithValue(context.Background(), requestIDKey{}, "1234")
+//	logger.InfoContext(ctx, "hello") // => {..., "msg":"hello", "request_id":"1234"}
+package contextattrs
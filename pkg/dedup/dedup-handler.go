@@ -0,0 +1,244 @@
+package dedup
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fabien-marty/slog-helpers/internal/bufferpool"
+)
+
+// Mode is an enumeration type that defines the possible modes of the (dedup) Handler.
+type Mode string
+
+// ModeConsecutive is a mode that only suppresses consecutive identical records.
+const ModeConsecutive Mode = "consecutive"
+
+// ModeWindow is a mode that suppresses identical records during Window after the first occurrence.
+const ModeWindow Mode = "window"
+
+// ModeDefault is the default mode of the (dedup) Handler.
+const ModeDefault = ModeConsecutive
+
+// WindowDefault is the default maximum duration of a streak of identical records.
+const WindowDefault = 10 * time.Second
+
+// KeyRepeated is the key of the attribute (added to summary records) with the number of suppressed records.
+const KeyRepeated = "repeated"
+
+// KeyRepeatedFirst is the key of the attribute (added to summary records) with the time of the first occurrence.
+const KeyRepeatedFirst = "repeated_first"
+
+// KeyRepeatedLast is the key of the attribute (added to summary records) with the time of the last occurrence.
+const KeyRepeatedLast = "repeated_last"
+
+// now is the clock used by the handler (overridden in tests).
+var now = time.Now
+
+var _ slog.Handler = &Handler{}
+
+// Options is a struct that contains the options for the (dedup) Handler.
+type Options struct {
+	Mode   Mode          // The mode of the (dedup) Handler (default to ModeDefault).
+	Window time.Duration // The maximum duration of a streak of identical records (default to WindowDefault).
+}
+
+type streak struct {
+	key     string
+	handler slog.Handler
+	record  slog.Record
+	started time.Time
+	count   int
+	first   time.Time
+	last    time.Time
+}
+
+// deduplicator is shared between a Handler and all the handlers derived from it (WithAttrs/WithGroup).
+type deduplicator struct {
+	opts       *Options
+	mutex      sync.Mutex
+	streaks    map[string]*streak // only one streak in ModeConsecutive
+	lastExpire time.Time
+}
+
+// Handler is a slog handler that suppresses duplicate log records.
+type Handler struct {
+	slog.Handler
+	scope        string // serialized attributes/groups added with WithAttrs/WithGroup
+	deduplicator *deduplicator
+}
+
+// New creates a new Handler.
+//
+// Note: you should call Flush (or Close) before exiting the program to get the last summaries.
+func New(originalHandler slog.Handler, options *Options) *Handler {
+	if options.Mode == "" {
+		options.Mode = ModeDefault
+	}
+	if options.Window <= 0 {
+		options.Window = WindowDefault
+	}
+	d := &deduplicator{
+		opts:    options,
+		streaks: map[string]*streak{},
+	}
+	return &Handler{
+		Handler:      originalHandler,
+		deduplicator: d,
+	}
+}
+
+func (dh *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{
+		Handler:      dh.Handler.WithGroup(name),
+		scope:        dh.scope + "{" + name,
+		deduplicator: dh.deduplicator,
+	}
+}
+
+func (dh *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	writeAttrs(buffer, attrs)
+	return &Handler{
+		Handler:      dh.Handler.WithAttrs(attrs),
+		scope:        dh.scope + buffer.String(),
+		deduplicator: dh.deduplicator,
+	}
+}
+
+func (dh *Handler) key(record slog.Record) string {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	buffer.WriteString(dh.scope)
+	buffer.WriteString("\x00")
+	buffer.WriteString(record.Level.String())
+	buffer.WriteString("\x00")
+	buffer.WriteString(record.Message)
+	buffer.WriteString("\x00")
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	writeAttrs(buffer, attrs)
+	return buffer.String()
+}
+
+func writeAttrs(buffer *bytes.Buffer, attrs []slog.Attr) {
+	for _, attr := range attrs {
+		buffer.WriteString(attr.Key)
+		buffer.WriteString("=")
+		buffer.WriteString(attr.Value.Resolve().String())
+		buffer.WriteString("\x00")
+	}
+}
+
+// Handle forwards the record to the original handler (see constructor) if it is not a duplicate.
+//
+// The summaries of the streaks ended by this record (or expired) are emitted first (with the given context).
+func (dh *Handler) Handle(context context.Context, record slog.Record) error {
+	d := dh.deduplicator
+	key := dh.key(record)
+	t := now()
+	d.mutex.Lock()
+	s, ok := d.streaks[key]
+	if ok && t.Sub(s.started) < d.opts.Window {
+		s.count++
+		s.last = record.Time
+		d.mutex.Unlock()
+		return nil
+	}
+	ended := []*streak{}
+	if ok {
+		ended = append(ended, s)
+		delete(d.streaks, key)
+	}
+	if t.Sub(d.lastExpire) >= d.opts.Window/2 {
+		ended = append(ended, d.expired(t, false)...)
+	}
+	if d.opts.Mode == ModeConsecutive {
+		for _, s := range d.streaks {
+			if s.key != key {
+				ended = append(ended, s)
+			}
+		}
+		clear(d.streaks)
+	}
+	d.streaks[key] = &streak{
+		key:     key,
+		handler: dh.Handler,
+		record:  record.Clone(),
+		started: t,
+		first:   record.Time,
+		last:    record.Time,
+	}
+	d.mutex.Unlock()
+	err := emitSummaries(context, ended)
+	if err != nil {
+		return err
+	}
+	return dh.Handler.Handle(context, record)
+}
+
+// Flush ends all the current streaks (and emits the corresponding summaries).
+func (dh *Handler) Flush(ctx context.Context) error {
+	d := dh.deduplicator
+	d.mutex.Lock()
+	ended := d.expired(now(), true)
+	d.mutex.Unlock()
+	return emitSummaries(ctx, ended)
+}
+
+// Close is the same than Flush (the Handler does not hold any other resource).
+func (dh *Handler) Close(ctx context.Context) error {
+	return dh.Flush(ctx)
+}
+
+// expired removes the expired streaks (or all of them if all is true) and returns them (sorted by first occurrence).
+//
+// The mutex must be held.
+func (d *deduplicator) expired(t time.Time, all bool) []*streak {
+	d.lastExpire = t
+	ended := []*streak{}
+	for key, s := range d.streaks {
+		if all || t.Sub(s.started) >= d.opts.Window {
+			ended = append(ended, s)
+			delete(d.streaks, key)
+		}
+	}
+	sort.Slice(ended, func(i, j int) bool {
+		return ended[i].first.Before(ended[j].first)
+	})
+	return ended
+}
+
+// emitSummaries emits a summary record for each streak with suppressed records.
+//
+// A summary record is a new record (without the PC of the first occurrence) with the level, message and
+// attributes of the first occurrence. It does not trigger a (misleading) stack trace.
+func emitSummaries(ctx context.Context, streaks []*streak) error {
+	for _, s := range streaks {
+		if s.count == 0 {
+			continue
+		}
+		record := slog.NewRecord(s.last, s.record.Level, s.record.Message, 0)
+		s.record.Attrs(func(attr slog.Attr) bool {
+			record.AddAttrs(attr)
+			return true
+		})
+		record.AddAttrs(
+			slog.Int(KeyRepeated, s.count),
+			slog.Time(KeyRepeatedFirst, s.first),
+			slog.Time(KeyRepeatedLast, s.last),
+		)
+		err := s.handler.Handle(ctx, record)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
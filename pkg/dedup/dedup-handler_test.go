@@ -0,0 +1,127 @@
+package dedup
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fabien-marty/slog-helpers/pkg/external"
+	"github.com/fabien-marty/slog-helpers/pkg/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+type collector struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (c *collector) get() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	res := c.messages
+	c.messages = nil
+	return res
+}
+
+func (c *collector) handler() slog.Handler {
+	return external.New(&external.Options{
+		StringifiedCallback: func(time time.Time, level slog.Level, message string, attrs []external.StringifiedAttr) error {
+			c.mutex.Lock()
+			defer c.mutex.Unlock()
+			for _, attr := range attrs {
+				switch attr.Key {
+				case KeyRepeatedFirst, KeyRepeatedLast:
+					message += " " + attr.Key
+				default:
+					message += " " + attr.String()
+				}
+			}
+			c.messages = append(c.messages, message)
+			return nil
+		},
+	})
+}
+
+func TestDedupHandlerConsecutive(t *testing.T) {
+	c := &collector{}
+	h := New(c.handler(), &Options{Window: time.Hour})
+	logger := slog.New(h)
+	for i := 0; i < 5; i++ {
+		logger.Error("connection refused", slog.String("host", "db"))
+	}
+	logger.Error("connection refused", slog.String("host", "cache"))
+	logger.Error("connection refused", slog.String("host", "cache"))
+	logger.With(slog.String("foo", "bar")).Error("connection refused", slog.String("host", "cache"))
+	logger.Info("connected")
+	logger.Info("connected")
+	assert.Equal(t, []string{
+		"connection refused host=db",
+		"connection refused host=db repeated=4 repeated_first repeated_last",
+		"connection refused host=cache",
+		"connection refused host=cache repeated=1 repeated_first repeated_last",
+		"connection refused foo=bar host=cache",
+		"connected",
+	}, c.get())
+	assert.NoError(t, h.Close(context.Background()))
+	assert.Equal(t, []string{"connected repeated=1 repeated_first repeated_last"}, c.get())
+}
+
+func TestDedupHandlerWindow(t *testing.T) {
+	c := &collector{}
+	h := New(c.handler(), &Options{Mode: ModeWindow, Window: time.Hour})
+	logger := slog.New(h)
+	for i := 0; i < 3; i++ {
+		logger.Error("connection refused")
+		logger.Info("retrying")
+	}
+	assert.Equal(t, []string{"connection refused", "retrying"}, c.get())
+	assert.NoError(t, h.Flush(context.Background()))
+	assert.Equal(t, []string{
+		"connection refused repeated=2 repeated_first repeated_last",
+		"retrying repeated=2 repeated_first repeated_last",
+	}, c.get())
+	logger.Info("retrying")
+	assert.Equal(t, []string{"retrying"}, c.get())
+	assert.NoError(t, h.Close(context.Background()))
+	assert.Equal(t, 0, len(c.get()))
+}
+
+func TestDedupHandlerExpiration(t *testing.T) {
+	fakeNow := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return fakeNow }
+	defer func() { now = time.Now }()
+	c := &collector{}
+	h := New(c.handler(), &Options{Mode: ModeWindow, Window: time.Minute})
+	logger := slog.New(h)
+	logger.Warn("flapping")
+	logger.Warn("flapping")
+	fakeNow = fakeNow.Add(2 * time.Minute)
+	assert.Equal(t, []string{"flapping"}, c.get()) // no background goroutine
+	logger.Info("other")
+	assert.Equal(t, []string{
+		"flapping repeated=1 repeated_first repeated_last",
+		"other",
+	}, c.get())
+}
+
+func TestDedupHandlerSummaryWithStackTraceHandler(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	handler := stacktrace.New(slog.NewTextHandler(buffer, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == KeyRepeatedFirst || a.Key == KeyRepeatedLast {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}), &stacktrace.Options{Mode: stacktrace.ModeAddAttr})
+	h := New(handler, &Options{})
+	logger := slog.New(h)
+	logger.Warn("boom")
+	logger.Warn("boom")
+	assert.NoError(t, h.Flush(context.Background()))
+	assert.Equal(t, "level=WARN msg=boom\nlevel=WARN msg=boom repeated=1\n", buffer.String()) // no leaked attribute
+
+}
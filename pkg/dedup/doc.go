@@ -0,0 +1,29 @@
+// dedup.Handler is a slog handler that suppresses duplicate log records and emits "repeated N times" summaries.
+//
+// It does not output the log record itself, it decorates another slog.Handler given in the New() method.
+//
+// Two records are identical if they have the same level, message and attributes (including the ones
+// added with WithAttrs/WithGroup). The first record is always forwarded, the next identical ones are held back
+// and counted. When the streak ends (or when Window expires), a summary record is emitted: a copy of the first
+// record with a "repeated" count and the timestamps of the first and last occurrences.
+//
+// There is no background goroutine: summaries are emitted by the next handled record (with its context) or by Flush/Close.
+//
+// Two modes are available:
+//   - ModeConsecutive (default): only consecutive identical records are suppressed, any different record ends the streak
+//   - ModeWindow: identical records are suppressed during Window after the first occurrence (even if other records are logged in between)
+//
+// Full example:
+//
+//	handler := slog.NewTextHandler(os.Stderr, nil)
+//	dedupHandler := New(handler, &Options{
+//		Window: time.Minute,
+//	})
+//	defer dedupHandler.Flush(context.Background())
+//	logger := slog.New(dedupHandler)
+//	for i := 0; i < 1000; i++ {
+//		logger.Error("connection refused", slog.String("host", "db"))
+//	}
+//	logger.Info("connected")
+//	// => "connection refused" is logged twice: first occurrence and summary with repeated=999
+package dedup
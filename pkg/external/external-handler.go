@@ -3,6 +3,7 @@ package external
 import (
 	"context"
 	"log/slog"
+	"runtime"
 	"time"
 
 	"github.com/fabien-marty/slog-helpers/internal/accumulator"
@@ -25,6 +26,7 @@ type Options struct {
 	Callback            Callback                 // If not nil, this callback will be used to handle the log records.
 	FlattenedCallback   FlattenedAttrsCallback   // If not nil, this callback (with flattened attributes) will be used to handle the log records.
 	StringifiedCallback StringifiedAttrsCallback // If not nil, this callback (with stringified and flattened attributes) will be used to handle the log records.
+	SourceAttr          bool                     // If true (and if AddSource is set in the HandlerOptions), the source of the record is given to the callback as a first attribute.
 }
 
 // Handler is an opaque type that implements the slog.Handler interface.
@@ -64,8 +66,39 @@ func (eh *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	return eh.cloneWithNewAccumulator(eh.Accumulator.WithAttrs(attrs))
 }
 
+// source returns the source location of the record (nil if unknown).
+func source(record slog.Record) *slog.Source {
+	if record.PC == 0 {
+		return nil
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+	return &slog.Source{
+		Function: frame.Function,
+		File:     frame.File,
+		Line:     frame.Line,
+	}
+}
+
+// Handle calls the configured callback.
+//
+// If SourceAttr is set in the Options and AddSource in the HandlerOptions, a first attribute with the slog.SourceKey key and a *slog.Source value is added
+// (it is stringified as "file:line" for the StringifiedCallback).
+//
+// If ReplaceAttr is set in the HandlerOptions, it is called (like in the standard library handlers) for the built-in
+// time, level, message and source attributes and for every non-group attribute (with the path of groups).
+// Renamed built-in attributes are given to the callback as normal attributes.
 func (eh *Handler) Handle(context context.Context, record slog.Record) error {
 	var attrs []slog.Attr = eh.Accumulator.AssembleWithRecordAttrs(record)
+	if eh.opts.SourceAttr && eh.opts.AddSource {
+		if src := source(record); src != nil {
+			attrs = append([]slog.Attr{slog.Any(slog.SourceKey, src)}, attrs...)
+		}
+	}
+	if eh.opts.ReplaceAttr != nil {
+		var extra []slog.Attr
+		record.Time, record.Level, record.Message, extra = replaceBuiltins(eh.opts.ReplaceAttr, record.Time, record.Level, record.Message)
+		attrs = append(extra, replaceAttrs(eh.opts.ReplaceAttr, nil, attrs)...)
+	}
 	if eh.opts.Callback != nil {
 		return eh.opts.Callback(record.Time, record.Level, record.Message, attrs)
 	}
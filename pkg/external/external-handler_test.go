@@ -2,6 +2,7 @@ package external
 
 import (
 	"log/slog"
+	"strings"
 	"testing"
 	"time"
 
@@ -119,3 +120,110 @@ func TestWithRealCopy(t *testing.T) {
 	logger.Info(logMessage, slog.String("foo3", "bar3"), slog.Group("zzz", slog.String("aaa", "bbb")))
 
 }
+
+func TestNewExternalHandlerAddSource(t *testing.T) {
+	var sattrs []StringifiedAttr
+	h := New(&Options{
+		HandlerOptions: slog.HandlerOptions{AddSource: true},
+		SourceAttr:     true,
+		StringifiedCallback: func(time time.Time, level slog.Level, message string, attrs []StringifiedAttr) error {
+			sattrs = attrs
+			return nil
+		},
+	})
+	slog.New(h).WithGroup("group").Info("hello", slog.String("foo", "bar"))
+	assert.Equal(t, 2, len(sattrs))
+	assert.Equal(t, slog.SourceKey, sattrs[0].Key)
+	assert.Regexp(t, `/external-handler_test\.go:\d+$`, sattrs[0].Value)
+	assert.Equal(t, "group.foo=bar", sattrs[1].String())
+	var fattrs []FlattenedAttr
+	h = New(&Options{
+		HandlerOptions: slog.HandlerOptions{AddSource: true},
+		SourceAttr:     true,
+		FlattenedCallback: func(time time.Time, level slog.Level, message string, attrs []FlattenedAttr) error {
+			fattrs = attrs
+			return nil
+		},
+	})
+	slog.New(h).Info("hello")
+	assert.Equal(t, 1, len(fattrs))
+	source, ok := fattrs[0].Value.Any().(*slog.Source)
+	assert.True(t, ok)
+	assert.Equal(t, "github.com/fabien-marty/slog-helpers/pkg/external.TestNewExternalHandlerAddSource", source.Function)
+	h = New(&Options{
+		HandlerOptions: slog.HandlerOptions{AddSource: true},
+		FlattenedCallback: func(time time.Time, level slog.Level, message string, attrs []FlattenedAttr) error {
+			fattrs = attrs
+			return nil
+		},
+	})
+	slog.New(h).Info("hello")
+	assert.Equal(t, 0, len(fattrs)) // no source attribute without SourceAttr
+}
+
+func TestNewExternalHandlerReplaceAttr(t *testing.T) {
+	var calls []string
+	replace := func(groups []string, a slog.Attr) slog.Attr {
+		calls = append(calls, strings.Join(append(groups, a.Key), "."))
+		switch {
+		case a.Key == slog.TimeKey && len(groups) == 0:
+			return slog.Attr{}
+		case a.Key == slog.MessageKey && len(groups) == 0:
+			return slog.String(slog.MessageKey, strings.ToUpper(a.Value.String()))
+		case a.Key == "password":
+			return slog.String("password", "REDACTED")
+		case a.Key == "drop":
+			return slog.Attr{}
+		}
+		return a
+	}
+	callback := func(time time.Time, level slog.Level, message string, attrs []slog.Attr) error {
+		assert.True(t, time.IsZero())
+		assert.Equal(t, slog.LevelInfo, level)
+		assert.Equal(t, "HELLO WORLD", message)
+		assert.Equal(t, 2, len(attrs))
+		assert.Equal(t, "foo=123", attrs[0].String())
+		assert.Equal(t, "group=[password=REDACTED zzz=[password=REDACTED]]", attrs[1].String())
+		return nil
+	}
+	h := New(&Options{
+		HandlerOptions: slog.HandlerOptions{
+			ReplaceAttr: replace,
+		},
+		Callback: callback,
+	})
+	logger := slog.New(h).With(slog.Int("foo", 123)).WithGroup("group").With(slog.String("password", "secret"))
+	logger.Info("hello world", slog.Group("zzz", slog.String("password", "secret")), slog.Group("empty", slog.String("drop", "x")))
+	assert.Equal(t, []string{"time", "level", "msg", "foo", "group.password", "group.zzz.password", "group.empty.drop"}, calls)
+}
+
+func TestNewExternalHandlerReplaceAttrRenamedBuiltins(t *testing.T) {
+	replace := func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 {
+			switch a.Key {
+			case slog.LevelKey:
+				return slog.String("severity", a.Value.String())
+			case slog.MessageKey:
+				return slog.String("message", a.Value.String())
+			}
+		}
+		return a
+	}
+	callback := func(time time.Time, level slog.Level, message string, attrs []FlattenedAttr) error {
+		assert.False(t, time.IsZero())
+		assert.Equal(t, slog.LevelWarn, level)
+		assert.Equal(t, "", message)
+		assert.Equal(t, 3, len(attrs))
+		assert.Equal(t, "severity=WARN", attrs[0].String())
+		assert.Equal(t, "message=hello", attrs[1].String())
+		assert.Equal(t, "foo=bar", attrs[2].String())
+		return nil
+	}
+	h := New(&Options{
+		HandlerOptions: slog.HandlerOptions{
+			ReplaceAttr: replace,
+		},
+		FlattenedCallback: callback,
+	})
+	slog.New(h).Warn("hello", slog.String("foo", "bar"))
+}
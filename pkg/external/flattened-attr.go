@@ -11,6 +11,17 @@ type FlattenedAttr struct {
 	slog.Attr
 }
 
+// Stringified returns the StringifiedAttr corresponding to the FlattenedAttr.
+func (fa FlattenedAttr) Stringified() StringifiedAttr {
+	return newStringifiedAttr(fa)
+}
+
+// Flatten returns the flattened attributes (with group prefixes in keys) of the given attributes
+// (as given to a Callback).
+func Flatten(attrs []slog.Attr) []FlattenedAttr {
+	return newFlattenedAttrs(attrs, "")
+}
+
 // newFlattenedAttr creates a new FlattenedAttr from a slog.Attr and a currentGroup (can be empty).
 //
 // WARNING: attr must not be a group!
@@ -34,3 +34,10 @@ func TestNewFlattenedAttrs(t *testing.T) {
 	assert.Equal(t, "group1.group2.group2key", fattrs[2].Key)
 	assert.Equal(t, "group2value", fattrs[2].Value.String())
 }
+
+func TestFlatten(t *testing.T) {
+	fattrs := Flatten([]slog.Attr{slog.Group("group1", slog.Int("key", 1)), slog.String("key", "value")})
+	assert.Equal(t, 2, len(fattrs))
+	assert.Equal(t, "group1.key=1", fattrs[0].String())
+	assert.Equal(t, "key=value", fattrs[1].String())
+}
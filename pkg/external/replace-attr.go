@@ -0,0 +1,75 @@
+package external
+
+import (
+	"log/slog"
+	"time"
+)
+
+// replaceAttrFunc is the type of slog.HandlerOptions.ReplaceAttr.
+type replaceAttrFunc func(groups []string, a slog.Attr) slog.Attr
+
+// replaceAttrs calls replace on every non-group attribute (recursively, with the path of groups) like
+// the standard library handlers do. Removed attributes and empty groups are dropped.
+func replaceAttrs(replace replaceAttrFunc, groups []string, attrs []slog.Attr) []slog.Attr {
+	res := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		attr.Value = attr.Value.Resolve()
+		if attr.Value.Kind() == slog.KindGroup {
+			childGroups := groups
+			if attr.Key != "" {
+				childGroups = append(groups[:len(groups):len(groups)], attr.Key)
+			}
+			children := replaceAttrs(replace, childGroups, attr.Value.Group())
+			if len(children) > 0 {
+				res = append(res, slog.Attr{Key: attr.Key, Value: slog.GroupValue(children...)})
+			}
+			continue
+		}
+		attr = replace(groups, attr)
+		if attr.Key == "" {
+			continue
+		}
+		res = append(res, attr)
+	}
+	return res
+}
+
+// replaceBuiltins calls replace on the built-in attributes (time, level and message) and returns their new values.
+//
+// If a built-in attribute is renamed (or if its value has not the expected type), it is returned in extra
+// (to be rendered as a normal attribute). The level can't be removed.
+func replaceBuiltins(replace replaceAttrFunc, t time.Time, level slog.Level, message string) (time.Time, slog.Level, string, []slog.Attr) {
+	var extra []slog.Attr
+	if !t.IsZero() {
+		attr := replace(nil, slog.Time(slog.TimeKey, t))
+		attr.Value = attr.Value.Resolve()
+		switch {
+		case attr.Key == "":
+			t = time.Time{}
+		case attr.Key == slog.TimeKey && attr.Value.Kind() == slog.KindTime:
+			t = attr.Value.Time()
+		default:
+			t = time.Time{}
+			extra = append(extra, attr)
+		}
+	}
+	attr := replace(nil, slog.Any(slog.LevelKey, level))
+	attr.Value = attr.Value.Resolve()
+	if l, ok := attr.Value.Any().(slog.Level); ok && attr.Key == slog.LevelKey {
+		level = l
+	} else if attr.Key != slog.LevelKey && attr.Key != "" {
+		extra = append(extra, attr)
+	}
+	attr = replace(nil, slog.String(slog.MessageKey, message))
+	attr.Value = attr.Value.Resolve()
+	switch attr.Key {
+	case "":
+		message = ""
+	case slog.MessageKey:
+		message = attr.Value.String()
+	default:
+		message = ""
+		extra = append(extra, attr)
+	}
+	return t, level, message, extra
+}
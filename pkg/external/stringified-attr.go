@@ -1,5 +1,10 @@
 package external
 
+import (
+	"fmt"
+	"log/slog"
+)
+
 // StringifiedAttr is a struct that represents a stringified slog.Attr attribute.
 //
 // This is exactly the same than FlattenedAttr but the Value is resolved to a string.
@@ -9,9 +14,16 @@ type StringifiedAttr struct {
 }
 
 func newStringifiedAttr(attr FlattenedAttr) StringifiedAttr {
+	value := attr.Value.Resolve()
+	if source, ok := value.Any().(*slog.Source); ok && value.Kind() == slog.KindAny && source != nil {
+		return StringifiedAttr{
+			Key:   attr.Key,
+			Value: fmt.Sprintf("%s:%d", source.File, source.Line),
+		}
+	}
 	return StringifiedAttr{
 		Key:   attr.Key,
-		Value: attr.Value.Resolve().String(),
+		Value: value.String(),
 	}
 }
 
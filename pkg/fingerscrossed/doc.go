@@ -0,0 +1,24 @@
+// fingerscrossed.Handler is a slog handler that buffers low level records per unit of work and only outputs them if an error occurs.
+//
+// It does not output the log record itself, it decorates another slog.Handler given in the New() method.
+//
+// A unit of work (for example an HTTP request) is started with NewContext. Then, for records logged with this context
+// (InfoContext, DebugContext...):
+//   - records enabled by the original handler are forwarded as usual
+//   - records not enabled by the original handler (but with a level >= BufferLevel) are buffered in the context
+//   - when a record with a level >= TriggerLevel is logged, the buffered records are forwarded first
+//     (whatever the level of the original handler) and the next records of the unit of work are not buffered anymore
+//
+// If the unit of work succeeds, the buffered records are just garbage collected with the context (or dropped with Discard).
+//
+// It composes well with the stacktrace.Handler (use it as the original handler): errors arrive with the debug trail that led to them.
+//
+// Full example:
+//
+//	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo})
+//	logger := slog.New(New(handler, &Options{}))
+//	ctx := NewContext(context.Background())
+//	logger.DebugContext(ctx, "buffered, not written")
+//	logger.InfoContext(ctx, "written")
+//	logger.ErrorContext(ctx, "error") // => "buffered, not written" is written before "error"
+package fingerscrossed
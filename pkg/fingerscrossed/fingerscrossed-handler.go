@@ -0,0 +1,166 @@
+package fingerscrossed
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// TriggerLevelDefault is the default level from which the buffered records are flushed.
+const TriggerLevelDefault = slog.LevelError
+
+// BufferLevelDefault is the default minimal level of buffered records.
+const BufferLevelDefault = slog.LevelDebug
+
+// MaxRecordsDefault is the default maximum number of buffered records per unit of work.
+const MaxRecordsDefault = 1000
+
+var _ slog.Handler = &Handler{}
+
+// Options is a struct that contains the options for the (fingers crossed) Handler.
+type Options struct {
+	TriggerLevel slog.Leveler // The level from which the buffered records are flushed (default to TriggerLevelDefault).
+	BufferLevel  slog.Leveler // The minimal level of buffered records (default to BufferLevelDefault).
+	MaxRecords   int          // The maximum number of buffered records per unit of work, the oldest ones are dropped (default to MaxRecordsDefault).
+}
+
+type contextKey struct{}
+
+type entry struct {
+	context context.Context
+	handler slog.Handler
+	record  slog.Record
+}
+
+// buffer holds the buffered records of a unit of work.
+type buffer struct {
+	mutex     sync.Mutex
+	entries   []entry
+	triggered bool
+}
+
+// Handler is a slog handler that buffers low level records per unit of work and only outputs them if an error occurs.
+type Handler struct {
+	slog.Handler
+	opts *Options
+}
+
+// New creates a new Handler.
+func New(originalHandler slog.Handler, options *Options) *Handler {
+	if options.TriggerLevel == nil {
+		options.TriggerLevel = TriggerLevelDefault
+	}
+	if options.BufferLevel == nil {
+		options.BufferLevel = BufferLevelDefault
+	}
+	if options.MaxRecords <= 0 {
+		options.MaxRecords = MaxRecordsDefault
+	}
+	return &Handler{
+		Handler: originalHandler,
+		opts:    options,
+	}
+}
+
+// NewContext returns a copy of ctx which starts a new unit of work (with an empty buffer).
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, &buffer{})
+}
+
+func bufferFromContext(ctx context.Context) *buffer {
+	if ctx == nil {
+		return nil
+	}
+	b, _ := ctx.Value(contextKey{}).(*buffer)
+	return b
+}
+
+// Flush forwards the records buffered in the unit of work of ctx (and stops buffering for this unit of work).
+//
+// It does nothing if ctx is not a unit of work context (see NewContext).
+func Flush(ctx context.Context) error {
+	b := bufferFromContext(ctx)
+	if b == nil {
+		return nil
+	}
+	return b.flush()
+}
+
+// Discard drops the records buffered in the unit of work of ctx.
+//
+// It does nothing if ctx is not a unit of work context (see NewContext).
+func Discard(ctx context.Context) {
+	b := bufferFromContext(ctx)
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.entries = nil
+}
+
+func (b *buffer) flush() error {
+	b.mutex.Lock()
+	entries := b.entries
+	b.entries = nil
+	b.triggered = true
+	b.mutex.Unlock()
+	for _, e := range entries {
+		err := e.handler.Handle(e.context, e.record)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add buffers the record (and returns true) if the unit of work is not triggered yet.
+func (b *buffer) add(e entry, maxRecords int) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.triggered {
+		return false
+	}
+	if len(b.entries) >= maxRecords {
+		b.entries = b.entries[1:]
+	}
+	b.entries = append(b.entries, e)
+	return true
+}
+
+func (fh *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	if fh.Handler.Enabled(ctx, level) {
+		return true
+	}
+	return level >= fh.opts.BufferLevel.Level() && bufferFromContext(ctx) != nil
+}
+
+func (fh *Handler) WithGroup(name string) slog.Handler {
+	return New(fh.Handler.WithGroup(name), fh.opts)
+}
+
+func (fh *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return New(fh.Handler.WithAttrs(attrs), fh.opts)
+}
+
+// Handle forwards, buffers or flushes (then forwards) the record depending on its level and on the context.
+func (fh *Handler) Handle(ctx context.Context, record slog.Record) error {
+	b := bufferFromContext(ctx)
+	if b == nil {
+		return fh.Handler.Handle(ctx, record)
+	}
+	if record.Level >= fh.opts.TriggerLevel.Level() {
+		err := b.flush()
+		if err != nil {
+			return err
+		}
+		return fh.Handler.Handle(ctx, record)
+	}
+	if fh.Handler.Enabled(ctx, record.Level) {
+		return fh.Handler.Handle(ctx, record)
+	}
+	if b.add(entry{context: ctx, handler: fh.Handler, record: record.Clone()}, fh.opts.MaxRecords) {
+		return nil
+	}
+	return fh.Handler.Handle(ctx, record) // already triggered
+}
@@ -0,0 +1,93 @@
+package fingerscrossed
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/fabien-marty/slog-helpers/internal/bufferpool"
+	"github.com/fabien-marty/slog-helpers/pkg/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLogger(buffer *strings.Builder) *slog.Logger {
+	handler := slog.NewTextHandler(buffer, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	return slog.New(New(handler, &Options{}))
+}
+
+func TestFingersCrossedHandlerSuccess(t *testing.T) {
+	buffer := &strings.Builder{}
+	logger := newLogger(buffer)
+	ctx := NewContext(context.Background())
+	logger.DebugContext(ctx, "debug")
+	logger.InfoContext(ctx, "info")
+	logger.Debug("debug without unit of work")
+	assert.Equal(t, "level=INFO msg=info\n", buffer.String())
+	Discard(ctx)
+	logger.ErrorContext(ctx, "error")
+	assert.Equal(t, "level=INFO msg=info\nlevel=ERROR msg=error\n", buffer.String())
+}
+
+func TestFingersCrossedHandlerTrigger(t *testing.T) {
+	buffer := &strings.Builder{}
+	logger := newLogger(buffer).With(slog.String("foo", "bar"))
+	ctx := NewContext(context.Background())
+	logger.DebugContext(ctx, "debug1")
+	logger.InfoContext(ctx, "info")
+	logger.WithGroup("group").DebugContext(ctx, "debug2", slog.Int("key", 1))
+	logger.InfoContext(context.Background(), "other unit of work")
+	logger.ErrorContext(ctx, "error")
+	logger.DebugContext(ctx, "debug3")
+	assert.Equal(t, strings.Join([]string{
+		"level=INFO msg=info foo=bar",
+		"level=INFO msg=\"other unit of work\" foo=bar",
+		"level=DEBUG msg=debug1 foo=bar",
+		"level=DEBUG msg=debug2 foo=bar group.key=1",
+		"level=ERROR msg=error foo=bar",
+		"level=DEBUG msg=debug3 foo=bar",
+		"",
+	}, "\n"), buffer.String())
+}
+
+func TestFingersCrossedHandlerMaxRecords(t *testing.T) {
+	buffer := &strings.Builder{}
+	logger := slog.New(New(slog.NewTextHandler(buffer, nil), &Options{MaxRecords: 2}))
+	ctx := NewContext(context.Background())
+	logger.DebugContext(ctx, "debug1")
+	logger.DebugContext(ctx, "debug2")
+	logger.DebugContext(ctx, "debug3")
+	assert.Equal(t, 0, buffer.Len())
+	assert.NoError(t, Flush(ctx))
+	output := buffer.String()
+	assert.NotContains(t, output, "debug1")
+	assert.Contains(t, output, "debug2")
+	assert.Contains(t, output, "debug3")
+	assert.NoError(t, Flush(context.Background()))
+}
+
+func TestFingersCrossedHandlerWithStackTrace(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	handler := stacktrace.New(slog.NewTextHandler(buffer, nil), &stacktrace.Options{
+		Mode:           stacktrace.ModePrint,
+		WriterForPrint: buffer,
+	})
+	logger := slog.New(New(handler, &Options{}))
+	ctx := NewContext(context.Background())
+	logger.DebugContext(ctx, "debug")
+	logger.ErrorContext(ctx, "error")
+	output := buffer.String()
+	debugIndex := strings.Index(output, "msg=debug")
+	errorIndex := strings.Index(output, "msg=error")
+	stackIndex := strings.Index(output, "stacktrace enabled")
+	assert.True(t, debugIndex >= 0 && debugIndex < errorIndex && errorIndex < stackIndex)
+}
@@ -0,0 +1,195 @@
+package human
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/fabien-marty/slog-helpers/internal/ansi"
+)
+
+// errorMaxDepth is the maximum depth of rendered error trees (to protect against cyclic errors).
+const errorMaxDepth = 32
+
+// unwrapError returns the direct causes of an error (see errors.Unwrap and errors.Join).
+func unwrapError(err error) []error {
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		if cause := e.Unwrap(); cause != nil {
+			return []error{cause}
+		}
+	case interface{ Unwrap() []error }:
+		causes := make([]error, 0, len(e.Unwrap()))
+		for _, cause := range e.Unwrap() {
+			if cause != nil {
+				causes = append(causes, cause)
+			}
+		}
+		return causes
+	}
+	return nil
+}
+
+// errorOwnMessage returns the part of the message of an error which is not the message of its causes
+// (for example "open config" for fmt.Errorf("open config: %w", cause)).
+func errorOwnMessage(err error, causes []error) string {
+	message := err.Error()
+	if len(causes) == 1 {
+		causeMessage := causes[0].Error()
+		if message == causeMessage {
+			return ""
+		}
+		if own, found := strings.CutSuffix(message, ": "+causeMessage); found {
+			return own
+		}
+	}
+	if len(causes) > 1 {
+		causeMessages := make([]string, len(causes))
+		for i, cause := range causes {
+			causeMessages[i] = cause.Error()
+		}
+		if message == strings.Join(causeMessages, "\n") {
+			return ""
+		}
+	}
+	return message
+}
+
+// errorStack returns the stack trace carried by an error (with a StackTrace() method like tracerr errors,
+// returning []tracerr.Frame, or pkg/errors errors, returning a slice of program counters).
+func errorStack(err error) []*slog.Source {
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return nil
+	}
+	stack := method.Call(nil)[0]
+	if stack.Kind() != reflect.Slice || stack.Len() == 0 {
+		return nil
+	}
+	var sources []*slog.Source
+	switch elemType := stack.Type().Elem(); elemType.Kind() {
+	case reflect.Uintptr:
+		pcs := make([]uintptr, stack.Len())
+		for i := range pcs {
+			pcs[i] = uintptr(stack.Index(i).Uint())
+		}
+		frames := runtime.CallersFrames(pcs)
+		for {
+			frame, more := frames.Next()
+			if frame.Function != "" {
+				sources = append(sources, &slog.Source{Function: frame.Function, File: frame.File, Line: frame.Line})
+			}
+			if !more {
+				break
+			}
+		}
+	case reflect.Struct:
+		function, okFunction := elemType.FieldByName("Func")
+		file, okFile := elemType.FieldByName("Path")
+		line, okLine := elemType.FieldByName("Line")
+		if !okFunction || !okFile || !okLine || function.Type.Kind() != reflect.String || file.Type.Kind() != reflect.String || line.Type.Kind() != reflect.Int {
+			return nil
+		}
+		for i := 0; i < stack.Len(); i++ {
+			frame := stack.Index(i)
+			sources = append(sources, &slog.Source{
+				Function: frame.FieldByIndex(function.Index).String(),
+				File:     frame.FieldByIndex(file.Index).String(),
+				Line:     int(frame.FieldByIndex(line.Index).Int()),
+			})
+		}
+	}
+	return sources
+}
+
+// hasErrorStack returns true if the error (or one of its causes) carries a stack trace.
+func hasErrorStack(err error, depth int) bool {
+	if depth > errorMaxDepth {
+		return false
+	}
+	if len(errorStack(err)) > 0 {
+		return true
+	}
+	for _, cause := range unwrapError(err) {
+		if hasErrorStack(cause, depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorRenderer renders an error as a tree of causes (with the colors of the theme if not nil).
+type errorRenderer struct {
+	theme    *Theme
+	glyphs   *Glyphs
+	sanitize func(string) string
+	lines    []string
+}
+
+func (er *errorRenderer) style(style string, s string) string {
+	if er.theme == nil || style == "" {
+		return s
+	}
+	return style + s + ansi.Reset
+}
+
+func singleLine(s string) string {
+	return strings.ReplaceAll(s, "\n", " | ")
+}
+
+func (er *errorRenderer) node(err error, prefix string, childPrefix string, depth int) {
+	var dimStyle, errorStyle string
+	if er.theme != nil {
+		dimStyle, errorStyle = er.theme.Source, er.theme.ErrorValue
+	}
+	causes := unwrapError(err)
+	if depth >= errorMaxDepth {
+		causes = nil
+	}
+	line := er.style(dimStyle, prefix) + er.style(dimStyle, fmt.Sprintf("%T", err))
+	if own := errorOwnMessage(err, causes); own != "" {
+		line += er.style(dimStyle, ": ") + er.style(errorStyle, er.sanitize(singleLine(own)))
+	}
+	er.lines = append(er.lines, line)
+	stackPrefix := childPrefix + strings.Repeat(" ", textWidth(er.glyphs.TreeLast))
+	if len(causes) > 0 {
+		stackPrefix = childPrefix + er.glyphs.TreeLine
+	}
+	hasInnerStack := false
+	for _, cause := range causes {
+		hasInnerStack = hasInnerStack || hasErrorStack(cause, depth+1)
+	}
+	if !hasInnerStack {
+		// we only render the deepest stack traces (wrappers like pkg/errors add a stack trace at each level)
+		for _, source := range errorStack(err) {
+			er.lines = append(er.lines, er.style(dimStyle, stackPrefix+"at "+formatSource(source)))
+		}
+	}
+	for i, cause := range causes {
+		if i == len(causes)-1 {
+			er.node(cause, childPrefix+er.glyphs.TreeLast, childPrefix+strings.Repeat(" ", textWidth(er.glyphs.TreeLast)), depth+1)
+		} else {
+			er.node(cause, childPrefix+er.glyphs.TreeBranch, childPrefix+er.glyphs.TreeLine, depth+1)
+		}
+	}
+}
+
+// renderError returns the lines of the rich representation of an error: its message and then (if the error
+// wraps other errors or carries a stack trace) the tree of its causes with their types and stack traces.
+//
+// Messages are sanitized with the given function.
+func renderError(err error, theme *Theme, glyphs *Glyphs, sanitize func(string) string) []string {
+	er := &errorRenderer{theme: theme, glyphs: glyphs, sanitize: sanitize}
+	var errorStyle string
+	if theme != nil {
+		errorStyle = theme.ErrorValue
+	}
+	er.lines = append(er.lines, er.style(errorStyle, sanitize(singleLine(err.Error()))))
+	if len(unwrapError(err)) == 0 && len(errorStack(err)) == 0 {
+		return er.lines
+	}
+	er.node(err, "  ", "  ", 0)
+	return er.lines
+}
@@ -0,0 +1,99 @@
+package human
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/fabien-marty/tracerr"
+	"github.com/stretchr/testify/assert"
+)
+
+// pkgErrorsFrame and pkgErrorsError mimic the github.com/pkg/errors types.
+type pkgErrorsFrame uintptr
+
+type pkgErrorsError struct {
+	error
+	stack []pkgErrorsFrame
+}
+
+func (e *pkgErrorsError) StackTrace() []pkgErrorsFrame {
+	return e.stack
+}
+
+func (e *pkgErrorsError) Unwrap() error {
+	return e.error
+}
+
+func newPkgErrorsError(err error) error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(1, pcs)
+	stack := make([]pkgErrorsFrame, n)
+	for i := range stack {
+		stack[i] = pkgErrorsFrame(pcs[i])
+	}
+	return &pkgErrorsError{error: err, stack: stack}
+}
+
+func noSanitize(s string) string {
+	return s
+}
+
+func TestErrorOwnMessage(t *testing.T) {
+	root := errors.New("root")
+	wrapped := fmt.Errorf("layer: %w", root)
+	assert.Equal(t, "layer", errorOwnMessage(wrapped, unwrapError(wrapped)))
+	assert.Equal(t, "root", errorOwnMessage(root, unwrapError(root)))
+	joined := errors.Join(root, errors.New("other"))
+	assert.Equal(t, "", errorOwnMessage(joined, unwrapError(joined)))
+	custom := fmt.Errorf("%w (custom)", root)
+	assert.Equal(t, "root (custom)", errorOwnMessage(custom, unwrapError(custom)))
+}
+
+func TestRenderError(t *testing.T) {
+	root := &fs.PathError{Op: "open", Path: "/etc/foo", Err: fs.ErrPermission}
+	err := fmt.Errorf("load config: %w", errors.Join(root, errors.New("other")))
+	assert.Equal(t, []string{
+		"load config: open /etc/foo: permission denied | other",
+		"  *fmt.wrapError: load config",
+		"  └─ *errors.joinError",
+		"     ├─ *fs.PathError: open /etc/foo",
+		"     │  └─ *errors.errorString: permission denied",
+		"     └─ *errors.errorString: other",
+	}, renderError(err, nil, &GlyphsUnicode, noSanitize))
+	assert.Equal(t, []string{"foo"}, renderError(errors.New("foo"), nil, &GlyphsUnicode, noSanitize))
+}
+
+func TestRenderErrorStackTraces(t *testing.T) {
+	lines := renderError(fmt.Errorf("wrapped: %w", tracerr.New("boom")), nil, &GlyphsASCII, noSanitize)
+	assert.Equal(t, "wrapped: boom", lines[0])
+	assert.Equal(t, "  *fmt.wrapError: wrapped", lines[1])
+	assert.Equal(t, "  `- *tracerr.errorData", lines[2])
+	assert.Regexp(t, `^     \|  at pkg/human/error_test.go:\d+ \(TestRenderErrorStackTraces\)$`, lines[3])
+	lines = renderError(newPkgErrorsError(newPkgErrorsError(errors.New("boom"))), nil, &GlyphsASCII, noSanitize)
+	assert.Equal(t, "  *human.pkgErrorsError", lines[1])
+	assert.Equal(t, "  `- *human.pkgErrorsError", lines[2]) // only the deepest stack trace is rendered
+	assert.Regexp(t, `^     \|  at pkg/human/error_test.go:\d+ \(newPkgErrorsError\)$`, lines[3])
+}
+
+func TestHumanHandlerRichErrors(t *testing.T) {
+	buffer := &strings.Builder{}
+	richErrors := true
+	h := New(buffer, &Options{
+		TimeFormat: "-",
+		RichErrors: &richErrors,
+	})
+	logger := slog.New(h)
+	logger.Error("failed", slog.Any("err", errors.New("simple")), slog.String("foo", "bar"))
+	logger.Error("failed", slog.Any("err", fmt.Errorf("layer: %w", errors.New("root"))))
+	assert.Equal(t, `- [ERROR] failed {err=simple foo=bar}
+- [ERROR] failed
+    err=layer: root
+      *fmt.wrapError: layer
+      └─ *errors.errorString: root
+`, buffer.String())
+}
@@ -0,0 +1,139 @@
+package human
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Quoting is an enumeration type that defines how attribute values are quoted.
+type Quoting string
+
+// QuotingLogfmt quotes values (like logfmt) only if they are empty or contain spaces, quotes, "=", "{", "}"
+// or characters which must be escaped.
+const QuotingLogfmt Quoting = "logfmt"
+
+// QuotingAlways quotes all values.
+const QuotingAlways Quoting = "always"
+
+// QuotingNever never quotes values (but control characters are still escaped).
+const QuotingNever Quoting = "never"
+
+// QuotingDefault is the default quoting.
+const QuotingDefault = QuotingLogfmt
+
+// ansiRegexp matches ANSI escape sequences (CSI sequences like colors, OSC sequences like hyperlinks and other two characters sequences).
+var ansiRegexp = regexp.MustCompile("\x1b(?:\\[[0-?]*[ -/]*[@-~]|\\][^\x07\x1b]*(?:\x07|\x1b\\\\)|[@-Z\\\\-_])")
+
+// stripANSI removes the ANSI escape sequences of a string.
+func stripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return ansiRegexp.ReplaceAllString(s, "")
+}
+
+// isUnsafeRune returns true for runes which must be escaped (control characters and bidirectional formatting
+// characters which can be used to forge log lines, to alter the terminal or to hide text).
+func isUnsafeRune(r rune) bool {
+	return unicode.IsControl(r) || (r >= 0x202a && r <= 0x202e) || (r >= 0x2066 && r <= 0x2069)
+}
+
+// writeEscaped writes s with unsafe runes escaped (and with quotes and backslashes escaped if quoted is true).
+//
+// Invalid UTF-8 sequences are replaced by the unicode replacement character.
+func writeEscaped(sb *strings.Builder, s string, quoted bool) {
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case quoted && (r == '"' || r == '\\'):
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case isUnsafeRune(r) && r < 0x100:
+			fmt.Fprintf(sb, `\x%02x`, r)
+		case isUnsafeRune(r):
+			fmt.Fprintf(sb, `\u%04x`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+}
+
+// needsQuoting returns true if a value must be quoted with QuotingLogfmt.
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '"' || r == '=' || r == '{' || r == '}' || r == utf8.RuneError || isUnsafeRune(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeText returns a safe version of a text (ANSI sequences are removed with colors and unsafe characters are escaped).
+func (p *printer) sanitizeText(s string) string {
+	if p.opts.UseColors {
+		s = stripANSI(s)
+	}
+	if !needsQuoting(s) {
+		return s
+	}
+	var sb strings.Builder
+	writeEscaped(&sb, s, false)
+	return sb.String()
+}
+
+// sanitizeMessage returns a safe version of a message: like sanitizeText but the message is quoted if it contains
+// quotes or braces (so that it can't be confused with the attributes block).
+func (p *printer) sanitizeMessage(s string) string {
+	if p.opts.UseColors {
+		s = stripANSI(s)
+	}
+	return quote(s, strings.ContainsAny(s, `"{}`))
+}
+
+// sanitizeKey returns a safe version of an attribute key (quoted with the QuotingLogfmt rule whatever the Quoting option).
+func (p *printer) sanitizeKey(s string) string {
+	if p.opts.UseColors {
+		s = stripANSI(s)
+	}
+	return quote(s, needsQuoting(s))
+}
+
+// sanitizeValue returns a safe (and quoted depending on the Quoting option) version of an attribute value.
+func (p *printer) sanitizeValue(s string) string {
+	if p.opts.UseColors {
+		s = stripANSI(s)
+	}
+	quoted := false
+	switch p.opts.Quoting {
+	case QuotingAlways:
+		quoted = true
+	case QuotingNever:
+	default:
+		quoted = needsQuoting(s)
+	}
+	return quote(s, quoted)
+}
+
+// quote returns s with unsafe characters escaped, between quotes (and with quotes and backslashes escaped) if quoted is true.
+func quote(s string, quoted bool) string {
+	var sb strings.Builder
+	if quoted {
+		sb.WriteByte('"')
+	}
+	writeEscaped(&sb, s, quoted)
+	if quoted {
+		sb.WriteByte('"')
+	}
+	return sb.String()
+}
@@ -0,0 +1,101 @@
+package human
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripANSI(t *testing.T) {
+	assert.Equal(t, "foo bar", stripANSI("\x1b[31mfoo\x1b[0m \x1b[38;5;208mbar\x1b[0m"))
+	assert.Equal(t, "link", stripANSI("\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\"))
+	assert.Equal(t, "title", stripANSI("\x1b]0;evil\x07title"))
+}
+
+func TestNeedsQuoting(t *testing.T) {
+	assert.False(t, needsQuoting("foo"))
+	assert.False(t, needsQuoting("/foo/bar:12"))
+	assert.True(t, needsQuoting(""))
+	assert.True(t, needsQuoting("foo bar"))
+	assert.True(t, needsQuoting(`foo"bar`))
+	assert.True(t, needsQuoting("foo=bar"))
+	assert.True(t, needsQuoting("foo}"))
+	assert.True(t, needsQuoting("foo\nbar"))
+	assert.True(t, needsQuoting("foo\u202ebar"))
+	assert.True(t, needsQuoting("foo\xffbar"))
+}
+
+func TestSanitize(t *testing.T) {
+	p := &printer{opts: &Options{Quoting: QuotingLogfmt}}
+	assert.Equal(t, "foo", p.sanitizeValue("foo"))
+	assert.Equal(t, `""`, p.sanitizeValue(""))
+	assert.Equal(t, `"foo \"bar\" \\ baz"`, p.sanitizeValue(`foo "bar" \ baz`))
+	assert.Equal(t, `"foo\n2024-01-01T00:00:00Z [ERROR] fake\x1b[31m\u202e\x7f"`, p.sanitizeValue("foo\n2024-01-01T00:00:00Z [ERROR] fake\x1b[31m\u202e\x7f"))
+	assert.Equal(t, "\"a\uFFFDb\"", p.sanitizeValue("a\xffb"))
+	assert.Equal(t, `hello\nworld \x1b[31m`, p.sanitizeText("hello\nworld \x1b[31m"))
+	p = &printer{opts: &Options{Quoting: QuotingAlways}}
+	assert.Equal(t, `"foo"`, p.sanitizeValue("foo"))
+	p = &printer{opts: &Options{Quoting: QuotingNever}}
+	assert.Equal(t, `foo bar\nbaz`, p.sanitizeValue("foo bar\nbaz"))
+	p = &printer{opts: &Options{Quoting: QuotingLogfmt, UseColors: true}}
+	assert.Equal(t, "red", p.sanitizeValue("\x1b[31mred\x1b[0m"))
+	assert.Equal(t, "red", p.sanitizeText("\x1b[31mred\x1b[0m"))
+}
+
+func TestHumanHandlerLogInjection(t *testing.T) {
+	buffer := &strings.Builder{}
+	h := New(buffer, &Options{
+		TimeFormat: "-",
+	})
+	logger := slog.New(h)
+	logger.Info("hello\n- [ERROR] fake", slog.String("user", "bob}\n- [ERROR] fake {"), slog.String("key\x1b[2J", "value"))
+	assert.Equal(t, "- [INFO ] hello\\n- [ERROR] fake {user=\"bob}\\n- [ERROR] fake {\" \"key\\x1b[2J\"=value}\n", buffer.String())
+}
+
+func TestHumanHandlerBlockInjection(t *testing.T) {
+	buffer := &strings.Builder{}
+	h := New(buffer, &Options{
+		TimeFormat: "-",
+	})
+	logger := slog.New(h)
+	logger.Info("hello {admin=true}")
+	logger.Info("hello", slog.String("user", "bob"), slog.String("} {admin", "true"))
+	logger.Info("hello", slog.Group("g", slog.String("a=b c", "d")))
+	assert.Equal(t, "- [INFO ] \"hello {admin=true}\"\n"+
+		"- [INFO ] hello {user=bob \"} {admin\"=true}\n"+
+		"- [INFO ] hello {\"g.a=b c\"=d}\n", buffer.String())
+}
+
+func TestHumanHandlerMultiLineStrings(t *testing.T) {
+	buffer := &strings.Builder{}
+	prettyValues := true
+	h := New(buffer, &Options{
+		TimeFormat:   "-",
+		PrettyValues: &prettyValues,
+	})
+	slog.New(h).Info("hello", slog.String("stacktrace", "goroutine 1 [running]:\nmain.main()\n\t/foo/main.go:12\x1b[0m\n"))
+	assert.Equal(t, "- [INFO ] hello\n    stacktrace=\n      goroutine 1 [running]:\n      main.main()\n          /foo/main.go:12\\x1b[0m\n", buffer.String())
+}
+
+func TestHumanHandlerStackTraceBlocks(t *testing.T) {
+	buffer := &strings.Builder{}
+	h := New(buffer, &Options{
+		TimeFormat: "-",
+	})
+	stack := "goroutine 1 [running]:\nmain.main()\n\t/foo/main.go:12\x1b[0m\n"
+	slog.New(h).Info("hello", slog.String("other", "foo\nbar"), slog.String("stacktrace", stack))
+	slog.New(h).WithGroup("g").Info("hello", slog.String("stacktrace", stack))
+	assert.Equal(t, "- [INFO ] hello {other=\"foo\\nbar\"}\n    stacktrace=\n      goroutine 1 [running]:\n      main.main()\n          /foo/main.go:12\\x1b[0m\n"+
+		"- [INFO ] hello\n    g.stacktrace=\n      goroutine 1 [running]:\n      main.main()\n          /foo/main.go:12\\x1b[0m\n", buffer.String())
+	buffer.Reset()
+	prettyValues := false
+	h = New(buffer, &Options{
+		TimeFormat:   "-",
+		UseColors:    true,
+		PrettyValues: &prettyValues,
+	})
+	slog.New(h).Info("hello", slog.String("stacktrace", stack))
+	assert.Equal(t, []string{"▶ - [INFO ] hello", "      stacktrace=", "        goroutine 1 [running]:", "        main.main()", "            /foo/main.go:12", ""}, strings.Split(stripANSI(buffer.String()), "\n"))
+}
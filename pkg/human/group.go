@@ -0,0 +1,157 @@
+package human
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/fabien-marty/slog-helpers/internal/ansi"
+	"github.com/fabien-marty/slog-helpers/pkg/external"
+)
+
+// GroupLayout is an enumeration type that defines how groups of attributes are rendered.
+type GroupLayout string
+
+// GroupLayoutFlat renders the attributes of groups with dotted keys (group.key=value).
+const GroupLayoutFlat GroupLayout = "flat"
+
+// GroupLayoutTree renders groups as nested, indented blocks under the record.
+const GroupLayoutTree GroupLayout = "tree"
+
+// GroupLayoutCompact renders groups inline as group{key=value key2=value2}.
+const GroupLayoutCompact GroupLayout = "compact"
+
+// GroupLayoutDefault is the default group layout.
+const GroupLayoutDefault = GroupLayoutFlat
+
+// inlineGroups returns the attributes with the content of groups with an empty key inlined (like slog handlers)
+// and without empty groups.
+func inlineGroups(attrs []slog.Attr) []slog.Attr {
+	res := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		attr.Value = attr.Value.Resolve()
+		if attr.Value.Kind() != slog.KindGroup {
+			res = append(res, attr)
+			continue
+		}
+		if attr.Key == "" {
+			res = append(res, inlineGroups(attr.Value.Group())...)
+			continue
+		}
+		if len(attr.Value.Group()) > 0 {
+			res = append(res, attr)
+		}
+	}
+	return res
+}
+
+// splitAttrs splits the attributes between the ones rendered inline and the ones rendered as blocks
+// (depending on the group layout).
+func (p *printer) splitAttrs(attrs []slog.Attr, theme *Theme) ([]inlineAttr, []prettyBlock) {
+	if p.opts.GroupLayout != GroupLayoutTree && p.opts.GroupLayout != GroupLayoutCompact {
+		return p.splitFlattened(external.Flatten(attrs), theme)
+	}
+	inline := make([]inlineAttr, 0, len(attrs))
+	var blocks []prettyBlock
+	for _, attr := range inlineGroups(attrs) {
+		if attr.Value.Kind() != slog.KindGroup {
+			inlined, block := p.splitAttr(external.FlattenedAttr{Attr: attr}, theme)
+			if block != nil {
+				blocks = append(blocks, *block)
+			} else {
+				inline = append(inline, inlined)
+			}
+			continue
+		}
+		if p.opts.GroupLayout == GroupLayoutTree {
+			blocks = append(blocks, prettyBlock{key: p.sanitizeKey(attr.Key), sep: ":", lines: append([]string{""}, p.treeLines(attr.Value.Group(), theme, "  ")...)})
+			continue
+		}
+		plain, styled := p.compactGroup(attr.Value.Group(), theme)
+		inline = append(inline, inlineAttr{
+			StringifiedAttr: external.StringifiedAttr{Key: p.sanitizeKey(attr.Key), Value: plain},
+			group:           true,
+			styled:          styled,
+		})
+	}
+	return inline, blocks
+}
+
+// treeLines returns the lines of the attributes of a group (GroupLayoutTree), nested groups are indented.
+func (p *printer) treeLines(attrs []slog.Attr, theme *Theme, indent string) []string {
+	keyStyle, equalStyle, valueStyle := themeStyles(theme)
+	var lines []string
+	for _, attr := range inlineGroups(attrs) {
+		key := styled(theme, keyStyle, p.sanitizeKey(attr.Key))
+		if attr.Value.Kind() == slog.KindGroup {
+			lines = append(lines, indent+key+styled(theme, equalStyle, ":"))
+			lines = append(lines, p.treeLines(attr.Value.Group(), theme, indent+"  ")...)
+			continue
+		}
+		prefix := indent + key + styled(theme, equalStyle, "=")
+		inlined, block := p.splitAttr(external.FlattenedAttr{Attr: attr}, theme)
+		if block == nil {
+			style := valueStyle
+			if inlined.style != "" {
+				style = inlined.style
+			}
+			lines = append(lines, prefix+styled(theme, style, inlined.Value))
+			continue
+		}
+		for i, line := range block.lines {
+			if i == 0 {
+				lines = append(lines, prefix+line)
+			} else {
+				lines = append(lines, indent+line)
+			}
+		}
+	}
+	return lines
+}
+
+// compactGroup returns the compact representation ({key=value key2=value2}) of the attributes of a group,
+// without and with colors (keys and values are sanitized).
+func (p *printer) compactGroup(attrs []slog.Attr, theme *Theme) (plain string, colored string) {
+	keyStyle, equalStyle, valueStyle := themeStyles(theme)
+	var plainBuilder, coloredBuilder strings.Builder
+	plainBuilder.WriteString("{")
+	coloredBuilder.WriteString("{")
+	for i, attr := range inlineGroups(attrs) {
+		if i > 0 {
+			plainBuilder.WriteString(" ")
+			coloredBuilder.WriteString(" ")
+		}
+		key := p.sanitizeKey(attr.Key)
+		plainBuilder.WriteString(key)
+		coloredBuilder.WriteString(styled(theme, keyStyle, key))
+		if attr.Value.Kind() == slog.KindGroup {
+			childPlain, childColored := p.compactGroup(attr.Value.Group(), theme)
+			plainBuilder.WriteString(childPlain)
+			coloredBuilder.WriteString(childColored)
+			continue
+		}
+		value := p.sanitizeValue(external.FlattenedAttr{Attr: attr}.Stringified().Value)
+		plainBuilder.WriteString("=")
+		plainBuilder.WriteString(value)
+		coloredBuilder.WriteString(styled(theme, equalStyle, "="))
+		coloredBuilder.WriteString(styled(theme, valueStyle, value))
+	}
+	plainBuilder.WriteString("}")
+	coloredBuilder.WriteString("}")
+	return plainBuilder.String(), coloredBuilder.String()
+}
+
+// themeStyles returns the styles of keys, "=" and values (empty styles if the theme is nil).
+func themeStyles(theme *Theme) (key string, equal string, value string) {
+	if theme == nil {
+		return "", "", ""
+	}
+	return theme.Key, theme.Equal, theme.Value
+}
+
+// styled returns s with the given style (if the theme is not nil).
+func styled(theme *Theme, style string, s string) string {
+	if theme == nil || style == "" {
+		return s
+	}
+	return style + s + ansi.Reset
+}
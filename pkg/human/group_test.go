@@ -0,0 +1,49 @@
+package human
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func logGroups(layout GroupLayout, useColors bool) string {
+	buffer := &strings.Builder{}
+	h := New(buffer, &Options{
+		TimeFormat:  "-",
+		UseColors:   useColors,
+		Width:       -1,
+		GroupLayout: layout,
+	})
+	logger := slog.New(h).With(slog.String("request_id", "123"))
+	logger.WithGroup("http").Info("hello", slog.String("method", "GET"), slog.Int("status", 200), slog.Group("headers", slog.String("accept", "*/*")), slog.Group("empty"))
+	return buffer.String()
+}
+
+func TestGroupLayoutFlat(t *testing.T) {
+	assert.Equal(t, "- [INFO ] hello {request_id=123 http.method=GET http.status=200 http.headers.accept=*/*}\n", logGroups("", false))
+}
+
+func TestGroupLayoutCompact(t *testing.T) {
+	assert.Equal(t, "- [INFO ] hello {request_id=123 http{method=GET status=200 headers{accept=*/*}}}\n", logGroups(GroupLayoutCompact, false))
+	assert.Equal(t, "    ↳ request_id=123 http{method=GET status=200 headers{accept=*/*}}", strings.Split(stripANSI(logGroups(GroupLayoutCompact, true)), "\n")[1])
+}
+
+func TestGroupLayoutTree(t *testing.T) {
+	assert.Equal(t, `- [INFO ] hello {request_id=123}
+    http:
+      method=GET
+      status=200
+      headers:
+        accept=*/*
+`, logGroups(GroupLayoutTree, false))
+	assert.Equal(t, `▶ - [INFO ] hello
+    ↳ request_id=123
+      http:
+        method=GET
+        status=200
+        headers:
+          accept=*/*
+`, stripANSI(logGroups(GroupLayoutTree, true)))
+}
@@ -1,13 +1,16 @@
 package human
 
 import (
+	"fmt"
 	"io"
 	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/fabien-marty/slog-helpers/internal/ansi"
 	"github.com/fabien-marty/slog-helpers/internal/bufferpool"
+	"github.com/fabien-marty/slog-helpers/internal/term"
 	"github.com/fabien-marty/slog-helpers/pkg/external"
 )
 
@@ -20,32 +23,182 @@ type Handler struct {
 	external.Handler
 }
 
+// LevelFatal is the level of FATAL records (rendered as "[FATAL]", see also slogc.LevelFatal and slogc.Fatal).
+const LevelFatal = slog.LevelError + 4
+
+// TimeFormatDefault is the default time format (RFC 3339 with second precision).
+const TimeFormatDefault = "2006-01-02T15:04:05Z07:00"
+
+// TimeFormatMilli is the RFC 3339 time format with millisecond precision.
+const TimeFormatMilli = "2006-01-02T15:04:05.000Z07:00"
+
+// TimeFormatMicro is the RFC 3339 time format with microsecond precision.
+const TimeFormatMicro = "2006-01-02T15:04:05.000000Z07:00"
+
+// TimeFormatElapsed is a special time format to render the elapsed time since the process start.
+const TimeFormatElapsed = "elapsed"
+
+// TimeFormatDelta is a special time format to render the elapsed time since the previous record.
+const TimeFormatDelta = "delta"
+
+// processStart is used for TimeFormatElapsed.
+var processStart = time.Now()
+
 // Options is a struct that contains the options for the HumanHandler.
 type Options struct {
 	slog.HandlerOptions
-	UseColors bool // If true, use colors in the output.
+	UseColors    bool           // If true, use colors in the output.
+	TimeFormat   string         // A time layout (see time.Format) or TimeFormatElapsed/TimeFormatDelta (default to TimeFormatDefault).
+	TimeLocation *time.Location // The location used to render times (default to time.UTC).
+
+	SourcePlacement SourcePlacement // Where to render the source location when AddSource is set (default to SourcePlacementDefault).
+
+	Hyperlinks        *bool  // If true, source locations and URL values are OSC 8 hyperlinks (with colors only, nil => auto-detection of the terminal support).
+	EditorURLTemplate string // The URL template of source location hyperlinks (default to EditorURLTemplateDefault).
+
+	Theme  *Theme  // The color theme (with colors only, default to ThemeDefault).
+	Glyphs *Glyphs // The glyph set (default to GlyphsUnicode).
+
+	Width       int         // The width used to wrap attributes (with colors only, 0 => width of the terminal if the writer is a terminal, checked at most once per second, <0 => no wrapping).
+	AttrsLayout AttrsLayout // The layout of attributes (with colors only, default to AttrsLayoutDefault).
+
+	PrettyValues   *bool // If true, structs, maps, slices and JSON strings are rendered as indented blocks under the record (nil => only with colors), structs without exported fields are rendered inline with %+v, multi-line stack traces are always rendered as blocks.
+	PrettyMaxDepth int   // The maximum depth of pretty values (default to PrettyMaxDepthDefault).
+	PrettyMaxLines int   // The maximum number of lines of each pretty value (default to PrettyMaxLinesDefault).
+
+	RichErrors *bool // If true, error values are highlighted and their causes (with types and stack traces) are rendered as a tree under the record (nil => only with colors).
+
+	GroupLayout GroupLayout // How groups of attributes are rendered (default to GroupLayoutDefault).
+
+	// How attribute values are quoted (default to QuotingDefault).
+	//
+	// Whatever the quoting, control characters (newlines, escape...) of messages, keys and values are escaped
+	// (or ANSI escape sequences removed with colors) and invalid UTF-8 sequences are replaced, to prevent log injections.
+	// Keys are always quoted like values with QuotingLogfmt and messages are quoted if they contain quotes or braces.
+	Quoting Quoting
+}
+
+// EditorURLTemplateDefault is the default editor URL template (to open source files in the default application).
+const EditorURLTemplateDefault = ansi.EditorURLTemplateDefault
+
+// EditorURLTemplateVSCode is the editor URL template for Visual Studio Code.
+const EditorURLTemplateVSCode = ansi.EditorURLTemplateVSCode
+
+// EditorURLTemplateIdea is the editor URL template for JetBrains IDEs.
+const EditorURLTemplateIdea = ansi.EditorURLTemplateIdea
+
+// printer renders records, it is shared between a Handler and all the handlers derived from it (WithAttrs/WithGroup).
+type printer struct {
+	w           io.Writer
+	opts        *Options
+	hyperlinks  bool
+	terminal    bool
+	timeMutex   sync.Mutex
+	lastTime    time.Time // for TimeFormatDelta
+	widthMutex  sync.Mutex
+	widthTime   time.Time // last detection of the terminal width
+	cachedWidth int
 }
 
 // New creates a new HumanHandler.
 func New(w io.Writer, opts *Options) *Handler {
-	var callback external.StringifiedAttrsCallback
+	if opts.TimeFormat == "" {
+		opts.TimeFormat = TimeFormatDefault
+	}
+	if opts.TimeLocation == nil {
+		opts.TimeLocation = time.UTC
+	}
+	if opts.SourcePlacement == "" {
+		opts.SourcePlacement = SourcePlacementDefault
+	}
+	if opts.EditorURLTemplate == "" {
+		opts.EditorURLTemplate = EditorURLTemplateDefault
+	}
+	if opts.Theme == nil {
+		opts.Theme = &ThemeDefault
+	}
+	if opts.Glyphs == nil {
+		opts.Glyphs = &GlyphsUnicode
+	}
+	if opts.AttrsLayout == "" {
+		opts.AttrsLayout = AttrsLayoutDefault
+	}
+	if opts.PrettyValues == nil {
+		prettyValues := opts.UseColors
+		opts.PrettyValues = &prettyValues
+	}
+	if opts.Quoting == "" {
+		opts.Quoting = QuotingDefault
+	}
+	if opts.GroupLayout == "" {
+		opts.GroupLayout = GroupLayoutDefault
+	}
+	if opts.RichErrors == nil {
+		richErrors := opts.UseColors
+		opts.RichErrors = &richErrors
+	}
+	if opts.PrettyMaxDepth <= 0 {
+		opts.PrettyMaxDepth = PrettyMaxDepthDefault
+	}
+	if opts.PrettyMaxLines <= 0 {
+		opts.PrettyMaxLines = PrettyMaxLinesDefault
+	}
+	if opts.Hyperlinks == nil {
+		hyperlinks := opts.UseColors && ansi.SupportsHyperlinks()
+		opts.Hyperlinks = &hyperlinks
+	}
+	p := &printer{
+		w:          w,
+		opts:       opts,
+		hyperlinks: opts.UseColors && *opts.Hyperlinks,
+		terminal:   term.IsTerminal(w),
+	}
+	var callback external.Callback
 	if opts.UseColors {
-		callback = func(time time.Time, level slog.Level, message string, attrs []external.StringifiedAttr) error {
-			return handleColor(w, time, level, message, attrs)
-		}
+		callback = p.handleColor
 	} else {
-		callback = func(time time.Time, level slog.Level, message string, attrs []external.StringifiedAttr) error {
-			return handleNoColor(w, time, level, message, attrs)
-		}
+		callback = p.handleNoColor
 	}
 	return &Handler{
 		Handler: *external.New(&external.Options{
-			HandlerOptions:      opts.HandlerOptions,
-			StringifiedCallback: callback,
+			HandlerOptions: opts.HandlerOptions,
+			Callback:       callback,
+			SourceAttr:     true,
 		}),
 	}
 }
 
+// formatDuration renders a duration with a fixed width (microsecond precision).
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("+%12.6fs", d.Seconds())
+}
+
+// formatTime renders the time of a record (the result is blank if the time is zero).
+func (p *printer) formatTime(t time.Time) string {
+	var res string
+	switch p.opts.TimeFormat {
+	case TimeFormatElapsed:
+		res = formatDuration(t.Sub(processStart))
+	case TimeFormatDelta:
+		p.timeMutex.Lock()
+		if !p.lastTime.IsZero() && !t.IsZero() {
+			res = formatDuration(t.Sub(p.lastTime))
+		} else {
+			res = formatDuration(0)
+		}
+		if !t.IsZero() {
+			p.lastTime = t
+		}
+		p.timeMutex.Unlock()
+	default:
+		res = t.In(p.opts.TimeLocation).Format(p.opts.TimeFormat)
+	}
+	if t.IsZero() {
+		return strings.Repeat(" ", textWidth(res))
+	}
+	return res
+}
+
 func levelToStringNoColor(level slog.Level) string {
 	switch level {
 	case slog.LevelDebug:
@@ -56,97 +209,107 @@ func levelToStringNoColor(level slog.Level) string {
 		return "[WARN ]"
 	case slog.LevelError:
 		return "[ERROR]"
+	case LevelFatal:
+		return "[FATAL]"
 	}
 	return "[?????]"
 }
 
-func levelToString(level slog.Level) string {
-	switch level {
-	case slog.LevelDebug:
-		return ansi.Green + "[DEBUG]" + ansi.Reset
-	case slog.LevelInfo:
-		return ansi.Blue + "[INFO ]" + ansi.Reset
-	case slog.LevelWarn:
-		return ansi.Red + "[WARN ]" + ansi.Reset
-	case slog.LevelError:
-		return ansi.RedBackground + ansi.White + "[ERROR]" + ansi.Reset
-	}
-	return ansi.Cyan + "[?????]" + ansi.Reset
+func (p *printer) levelToString(level slog.Level) string {
+	return p.opts.Glyphs.level(level) + p.opts.Theme.level(level) + levelToStringNoColor(level) + ansi.Reset
 }
 
-func handleColor(w io.Writer, time time.Time, level slog.Level, message string, attrs []external.StringifiedAttr) error {
+func (p *printer) handleColor(time time.Time, level slog.Level, message string, attrs []slog.Attr) error {
 	buffer := bufferpool.Get()
 	defer bufferpool.Put(buffer)
-	ascTime := "              "
-	if !time.IsZero() {
-		ascTime = time.UTC().Format("2006-01-02T15:04:05Z")
-	}
-	buffer.WriteString("▶ ")
-	buffer.WriteString(ansi.Cyan)
+	ascTime := p.formatTime(time)
+	theme := p.opts.Theme
+	source, attrs := splitSource(attrs)
+	inline, blocks := p.splitAttrs(attrs, theme)
+	buffer.WriteString(p.opts.Glyphs.Record)
+	buffer.WriteString(theme.Time)
 	buffer.WriteString(ascTime)
 	buffer.WriteString(ansi.Reset)
 	buffer.WriteString(" ")
-	buffer.WriteString(levelToString(level))
+	buffer.WriteString(p.levelToString(level))
 	buffer.WriteString(" ")
-	buffer.WriteString(ansi.Bold)
-	buffer.WriteString(message)
-	buffer.WriteString(ansi.Reset)
-	nAttr := 0
-	if len(attrs) > 0 {
-		buffer.WriteString("\n    ↳ ")
-	}
-	for _, attr := range attrs {
-		buffer.WriteString(ansi.Yellow)
-		buffer.WriteString(attr.Key)
+	if source != nil && p.opts.SourcePlacement == SourcePlacementPrefix {
+		buffer.WriteString(theme.Source)
+		buffer.WriteString(p.formatSourceColor(source))
 		buffer.WriteString(ansi.Reset)
-		buffer.WriteString(ansi.Bold)
-		buffer.WriteString("=")
+		buffer.WriteString(" ")
+	}
+	buffer.WriteString(theme.Message)
+	buffer.WriteString(p.sanitizeMessage(message))
+	buffer.WriteString(ansi.Reset)
+	if source != nil && p.opts.SourcePlacement == SourcePlacementSuffix {
+		buffer.WriteString(" ")
+		buffer.WriteString(theme.Source)
+		buffer.WriteString(p.formatSourceColor(source))
 		buffer.WriteString(ansi.Reset)
-		buffer.WriteString(ansi.Magenta)
-		buffer.WriteString(attr.Value)
+	}
+	if source != nil && p.opts.SourcePlacement == SourcePlacementLine {
+		buffer.WriteString("\n    ")
+		buffer.WriteString(theme.Source)
+		buffer.WriteString(p.opts.Glyphs.Source)
+		buffer.WriteString(p.formatSourceColor(source))
 		buffer.WriteString(ansi.Reset)
-		nAttr++
-		if nAttr < len(attrs) {
-			buffer.WriteString(" ")
-		}
 	}
+	p.writeAttrsColor(buffer, inline)
+	writePrettyBlocks(buffer, blocks, strings.Repeat(" ", textWidth("    "+p.opts.Glyphs.Attrs)), theme)
 	buffer.WriteString("\n")
 	mutex.Lock()
 	defer mutex.Unlock()
-	_, err := w.Write(buffer.Bytes())
+	_, err := p.w.Write(buffer.Bytes())
 	return err
 }
 
-func handleNoColor(w io.Writer, time time.Time, level slog.Level, message string, attrs []external.StringifiedAttr) error {
+func (p *printer) handleNoColor(time time.Time, level slog.Level, message string, attrs []slog.Attr) error {
 	buffer := bufferpool.Get()
 	defer bufferpool.Put(buffer)
-	ascTime := "              "
-	if !time.IsZero() {
-		ascTime = time.UTC().Format("2006-01-02T15:04:05Z")
-	}
+	ascTime := p.formatTime(time)
+	source, attrs := splitSource(attrs)
+	inline, blocks := p.splitAttrs(attrs, nil)
 	buffer.WriteString(ascTime)
 	buffer.WriteString(" ")
+	buffer.WriteString(p.opts.Glyphs.level(level))
 	buffer.WriteString(levelToStringNoColor(level))
 	buffer.WriteString(" ")
-	buffer.WriteString(message)
+	if source != nil && p.opts.SourcePlacement == SourcePlacementPrefix {
+		buffer.WriteString(formatSource(source))
+		buffer.WriteString(" ")
+	}
+	buffer.WriteString(p.sanitizeMessage(message))
+	if source != nil && p.opts.SourcePlacement == SourcePlacementSuffix {
+		buffer.WriteString(" ")
+		buffer.WriteString(formatSource(source))
+	}
 	nAttr := 0
-	if len(attrs) > 0 {
+	if len(inline) > 0 {
 		buffer.WriteString(" {")
 	}
-	for _, attr := range attrs {
+	for _, attr := range inline {
 		buffer.WriteString(attr.Key)
-		buffer.WriteString("=")
+		if !attr.group {
+			buffer.WriteString("=")
+		}
 		buffer.WriteString(attr.Value)
 		nAttr++
-		if nAttr < len(attrs) {
+		if nAttr < len(inline) {
 			buffer.WriteString(" ")
 		} else {
 			buffer.WriteString("}")
 		}
 	}
+	if source != nil && p.opts.SourcePlacement == SourcePlacementLine {
+		buffer.WriteString("\n    ")
+		buffer.WriteString(p.opts.Glyphs.Source)
+		buffer.WriteString(formatSource(source))
+	}
+	writePrettyBlocks(buffer, blocks, "    ", nil)
 	buffer.WriteString("\n")
 	mutex.Lock()
 	defer mutex.Unlock()
-	_, err := w.Write(buffer.Bytes())
+	_, err := p.w.Write(buffer.Bytes())
 	return err
 }
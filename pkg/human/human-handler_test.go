@@ -1,11 +1,12 @@
 package human
 
 import (
+	"context"
 	"log/slog"
 	"strings"
 	"testing"
+	"time"
 
-	"github.com/fabien-marty/slog-helpers/internal/ansi"
 	"github.com/fabien-marty/slog-helpers/internal/bufferpool"
 	"github.com/stretchr/testify/assert"
 )
@@ -62,13 +63,79 @@ func TestLevelToStringNoColor(t *testing.T) {
 	assert.Equal(t, "[INFO ]", levelToStringNoColor(slog.LevelInfo))
 	assert.Equal(t, "[WARN ]", levelToStringNoColor(slog.LevelWarn))
 	assert.Equal(t, "[ERROR]", levelToStringNoColor(slog.LevelError))
+	assert.Equal(t, "[FATAL]", levelToStringNoColor(LevelFatal))
 	assert.Equal(t, "[?????]", levelToStringNoColor(slog.Level(42)))
 }
 
 func TestLevelToString(t *testing.T) {
-	assert.Equal(t, ansi.Green+"[DEBUG]"+ansi.Reset, levelToString(slog.LevelDebug))
-	assert.Equal(t, ansi.Blue+"[INFO ]"+ansi.Reset, levelToString(slog.LevelInfo))
-	assert.Equal(t, ansi.Red+"[WARN ]"+ansi.Reset, levelToString(slog.LevelWarn))
-	assert.Equal(t, ansi.RedBackground+ansi.White+"[ERROR]"+ansi.Reset, levelToString(slog.LevelError))
-	assert.Equal(t, ansi.Cyan+"[?????]"+ansi.Reset, levelToString(slog.Level(42)))
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	opts := &Options{UseColors: true}
+	_ = New(buffer, opts) // default theme and glyphs
+	p := &printer{opts: opts}
+	assert.Equal(t, "\033[32m[DEBUG]\033[0m", p.levelToString(slog.LevelDebug))
+	assert.Equal(t, "\033[34m[INFO ]\033[0m", p.levelToString(slog.LevelInfo))
+	assert.Equal(t, "\033[31m[WARN ]\033[0m", p.levelToString(slog.LevelWarn))
+	assert.Equal(t, "\033[41m\033[37m[ERROR]\033[0m", p.levelToString(slog.LevelError))
+	assert.Equal(t, "\033[41m\033[37m\033[1m[FATAL]\033[0m", p.levelToString(LevelFatal))
+	assert.Equal(t, "\033[36m[?????]\033[0m", p.levelToString(slog.Level(42)))
+}
+
+func TestHumanHandlerTimeFormat(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+	h := New(buffer, &Options{
+		TimeFormat:   TimeFormatMilli,
+		TimeLocation: paris,
+	})
+	record := slog.NewRecord(time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC), slog.LevelInfo, "hello", 0)
+	assert.NoError(t, h.Handle(context.Background(), record))
+	record.Time = time.Time{}
+	assert.NoError(t, h.Handle(context.Background(), record))
+	assert.Equal(t, "2024-01-02T04:04:05.123+01:00 [INFO ] hello\n                              [INFO ] hello\n", buffer.String())
+}
+
+func TestHumanHandlerTimeFormatDelta(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	h := New(buffer, &Options{
+		TimeFormat: TimeFormatDelta,
+	})
+	logger := slog.New(h).With(slog.String("foo", "bar")) // derived handlers share the previous time
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.NoError(t, logger.Handler().Handle(context.Background(), slog.NewRecord(start, slog.LevelInfo, "first", 0)))
+	assert.NoError(t, h.Handle(context.Background(), slog.NewRecord(start.Add(1500*time.Microsecond), slog.LevelInfo, "second", 0)))
+	assert.Equal(t, "+    0.000000s [INFO ] first {foo=bar}\n+    0.001500s [INFO ] second\n", buffer.String())
+}
+
+func TestHumanHandlerTimeFormatElapsed(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	h := New(buffer, &Options{
+		TimeFormat: TimeFormatElapsed,
+	})
+	assert.NoError(t, h.Handle(context.Background(), slog.NewRecord(processStart.Add(2*time.Second), slog.LevelInfo, "hello", 0)))
+	assert.Equal(t, "+    2.000000s [INFO ] hello\n", buffer.String())
+}
+
+func TestHumanHandlerReplaceAttr(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	h := New(buffer, &Options{
+		HandlerOptions: slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey && len(groups) == 0 {
+					return slog.Attr{}
+				}
+				if a.Key == "token" {
+					return slog.String(a.Key, "***")
+				}
+				return a
+			},
+		},
+	})
+	slog.New(h).WithGroup("auth").Info("login", slog.String("user", "bob"), slog.String("token", "secret"))
+	assert.Equal(t, "                     [INFO ] login {auth.user=bob auth.token=***}\n", buffer.String())
 }
@@ -0,0 +1,186 @@
+package human
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fabien-marty/slog-helpers/internal/ansi"
+	"github.com/fabien-marty/slog-helpers/internal/term"
+	"github.com/mattn/go-runewidth"
+)
+
+// AttrsLayout is an enumeration type that defines how attributes are rendered (with colors).
+type AttrsLayout string
+
+// AttrsLayoutInline renders attributes one after the other (wrapped at key boundaries if the width is known).
+const AttrsLayoutInline AttrsLayout = "inline"
+
+// AttrsLayoutColumn renders one attribute per line with aligned keys.
+const AttrsLayoutColumn AttrsLayout = "column"
+
+// AttrsLayoutDefault is the default attributes layout.
+const AttrsLayoutDefault = AttrsLayoutInline
+
+// keyColumnMaxWidth is the maximum width of the key column (with AttrsLayoutColumn), longer keys are not aligned.
+const keyColumnMaxWidth = 24
+
+// minValueWidth is the minimum width used to wrap long values (if there is less room, values overflow).
+const minValueWidth = 20
+
+// widthRefreshInterval is the minimum interval between two detections of the terminal width.
+const widthRefreshInterval = time.Second
+
+// now is the clock used to refresh the terminal width (overridden in tests).
+var now = time.Now
+
+// textWidth returns the number of columns of a string (without escape sequences), wide characters (CJK, emoji...)
+// use two columns.
+func textWidth(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// width returns the width used to wrap attributes (0 means no wrapping).
+//
+// The terminal width is detected at most once per widthRefreshInterval (so that a resized terminal is taken
+// into account without a system call per record).
+func (p *printer) width() int {
+	if p.opts.Width != 0 {
+		return max(p.opts.Width, 0)
+	}
+	if !p.terminal {
+		return 0
+	}
+	p.widthMutex.Lock()
+	defer p.widthMutex.Unlock()
+	if t := now(); p.widthTime.IsZero() || t.Sub(p.widthTime) >= widthRefreshInterval {
+		p.cachedWidth, _ = term.Width(p.w)
+		p.widthTime = t
+	}
+	return p.cachedWidth
+}
+
+// wrapText splits s in lines of (at most) first columns for the first line and rest columns for the others
+// (at spaces if possible).
+func wrapText(s string, first int, rest int) []string {
+	var lines []string
+	limit := first
+	for textWidth(s) > limit {
+		cut, space, width := len(s), -1, 0
+		for i, r := range s {
+			w := runewidth.RuneWidth(r)
+			if width+w > limit {
+				cut = i
+				if r == ' ' {
+					space = i
+				}
+				break
+			}
+			if r == ' ' {
+				space = i
+			}
+			width += w
+		}
+		if space > 0 {
+			lines = append(lines, s[:space])
+			s = s[space+1:]
+		} else {
+			if cut == 0 { // a single character wider than the limit
+				_, cut = utf8.DecodeRuneInString(s)
+			}
+			lines = append(lines, s[:cut])
+			s = s[cut:]
+		}
+		limit = rest
+	}
+	return append(lines, s)
+}
+
+// writeAttrsColor writes the attributes lines of a record (with colors) depending on the layout and on the width.
+func (p *printer) writeAttrsColor(buffer *bytes.Buffer, attrs []inlineAttr) {
+	if len(attrs) == 0 {
+		return
+	}
+	theme := p.opts.Theme
+	width := p.width()
+	prefix := "    " + p.opts.Glyphs.Attrs
+	indent := strings.Repeat(" ", textWidth(prefix))
+	keyWidth := 0
+	if p.opts.AttrsLayout == AttrsLayoutColumn {
+		for _, attr := range attrs {
+			if w := textWidth(attr.Key); w <= keyColumnMaxWidth {
+				keyWidth = max(keyWidth, w)
+			}
+		}
+	}
+	buffer.WriteString("\n")
+	buffer.WriteString(prefix)
+	col := len(indent)
+	for i, attr := range attrs {
+		key := attr.Key
+		value := attr.Value
+		itemWidth := textWidth(key) + 1 + textWidth(value)
+		if attr.group {
+			itemWidth--
+		}
+		if i > 0 {
+			switch {
+			case p.opts.AttrsLayout == AttrsLayoutColumn, width > 0 && col+1+itemWidth > width:
+				buffer.WriteString("\n")
+				buffer.WriteString(indent)
+				col = len(indent)
+			default:
+				buffer.WriteString(" ")
+				col++
+			}
+		}
+		buffer.WriteString(theme.Key)
+		buffer.WriteString(key)
+		buffer.WriteString(ansi.Reset)
+		col += textWidth(key)
+		if pad := keyWidth - textWidth(key); pad > 0 {
+			buffer.WriteString(strings.Repeat(" ", pad))
+			col += pad
+		}
+		if attr.group {
+			buffer.WriteString(attr.styled)
+			col += textWidth(value)
+			continue
+		}
+		buffer.WriteString(theme.Equal)
+		buffer.WriteString("=")
+		buffer.WriteString(ansi.Reset)
+		col++
+		valueStyle := theme.Value
+		if attr.style != "" {
+			valueStyle = attr.style
+		}
+		buffer.WriteString(valueStyle)
+		switch {
+		case p.hyperlinks && ansi.IsURL(value):
+			buffer.WriteString(ansi.Hyperlink(value, value))
+			col += textWidth(value)
+		case width > 0 && col+textWidth(value) > width && !strings.Contains(value, "\n"):
+			valueCol := col
+			if width-valueCol < minValueWidth {
+				valueCol = len(indent)
+			}
+			lines := wrapText(value, max(width-col, minValueWidth), max(width-valueCol, minValueWidth))
+			for j, line := range lines {
+				if j > 0 {
+					buffer.WriteString(ansi.Reset)
+					buffer.WriteString("\n")
+					buffer.WriteString(strings.Repeat(" ", valueCol))
+					buffer.WriteString(valueStyle)
+				}
+				buffer.WriteString(line)
+			}
+			col = valueCol + textWidth(lines[len(lines)-1])
+		default:
+			buffer.WriteString(value)
+			col += textWidth(value)
+		}
+		buffer.WriteString(ansi.Reset)
+	}
+}
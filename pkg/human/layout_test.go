@@ -0,0 +1,104 @@
+package human
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newLayoutTestHandler(buffer *strings.Builder, width int, layout AttrsLayout) slog.Handler {
+	return New(buffer, &Options{
+		HandlerOptions: slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		},
+		UseColors:   true,
+		Width:       width,
+		AttrsLayout: layout,
+	})
+}
+
+func TestWrapText(t *testing.T) {
+	assert.Equal(t, []string{"foo"}, wrapText("foo", 10, 10))
+	assert.Equal(t, []string{"foo bar", "baz qux", "quux"}, wrapText("foo bar baz qux quux", 8, 8))
+	assert.Equal(t, []string{"abc", "defgh", "ij"}, wrapText("abcdefghij", 3, 5))
+	assert.Equal(t, []string{"日本", "語の", "文"}, wrapText("日本語の文", 5, 4)) // wide characters use two columns
+	assert.Equal(t, []string{"ab 💀", "cd"}, wrapText("ab 💀 cd", 5, 5))
+}
+
+func TestTextWidth(t *testing.T) {
+	assert.Equal(t, 3, textWidth("foo"))
+	assert.Equal(t, 6, textWidth("日本語"))
+	assert.Equal(t, 3, textWidth(GlyphsEmoji.Fatal))
+	assert.Equal(t, 2, textWidth(GlyphsEmoji.Attrs))
+}
+
+func TestPrinterWidthCache(t *testing.T) {
+	current := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+	p := &printer{w: &strings.Builder{}, opts: &Options{}, terminal: true}
+	assert.Equal(t, 0, p.width()) // not a real terminal => not detected
+	p.cachedWidth = 42            // as if the terminal was resized after the detection
+	current = current.Add(widthRefreshInterval / 2)
+	assert.Equal(t, 42, p.width()) // cached
+	current = current.Add(widthRefreshInterval)
+	assert.Equal(t, 0, p.width()) // detected again
+}
+
+func TestHumanHandlerWrapWideAttrs(t *testing.T) {
+	buffer := &strings.Builder{}
+	logger := slog.New(newLayoutTestHandler(buffer, 24, ""))
+	logger.Info("hello", slog.String("foo", "日本語"), slog.String("bar", "日本語"))
+	lines := strings.Split(stripANSI(buffer.String()), "\n")
+	assert.Equal(t, []string{
+		"▶                      [INFO ] hello",
+		"    ↳ foo=日本語",
+		"      bar=日本語",
+		"",
+	}, lines)
+}
+
+func TestHumanHandlerWrapAttrs(t *testing.T) {
+	buffer := &strings.Builder{}
+	logger := slog.New(newLayoutTestHandler(buffer, 32, ""))
+	logger.Info("hello", slog.String("foo", "bar"), slog.String("foofoo", "barbar"), slog.String("k", "v"), slog.String("long", "aaa bbb ccc ddd eee fff ggg hhh iii"))
+	lines := strings.Split(stripANSI(buffer.String()), "\n")
+	assert.Equal(t, []string{
+		"▶                      [INFO ] hello",
+		"    ↳ foo=bar foofoo=barbar k=v",
+		"      long=\"aaa bbb ccc ddd eee",
+		"           fff ggg hhh iii\"",
+		"",
+	}, lines)
+}
+
+func TestHumanHandlerNoWrapByDefault(t *testing.T) {
+	buffer := &strings.Builder{}
+	logger := slog.New(newLayoutTestHandler(buffer, 0, ""))
+	logger.Info("hello", slog.String("foo", strings.Repeat("x", 200)), slog.String("bar", "baz"))
+	lines := strings.Split(stripANSI(buffer.String()), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.Equal(t, "    ↳ foo="+strings.Repeat("x", 200)+" bar=baz", lines[1])
+}
+
+func TestHumanHandlerColumnLayout(t *testing.T) {
+	buffer := &strings.Builder{}
+	logger := slog.New(newLayoutTestHandler(buffer, -1, AttrsLayoutColumn))
+	logger.Info("hello", slog.String("foo", "bar"), slog.String("foofoo", "barbar"), slog.String("k", "v"))
+	lines := strings.Split(stripANSI(buffer.String()), "\n")
+	assert.Equal(t, []string{
+		"▶                      [INFO ] hello",
+		"    ↳ foo   =bar",
+		"      foofoo=barbar",
+		"      k     =v",
+		"",
+	}, lines)
+}
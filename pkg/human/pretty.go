@@ -0,0 +1,398 @@
+package human
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/fabien-marty/slog-helpers/internal/ansi"
+	"github.com/fabien-marty/slog-helpers/pkg/external"
+	"github.com/fabien-marty/slog-helpers/pkg/stacktrace"
+)
+
+// PrettyMaxDepthDefault is the default maximum depth of pretty values (deeper objects and arrays are elided).
+const PrettyMaxDepthDefault = 6
+
+// PrettyMaxLinesDefault is the default maximum number of lines of pretty values (longer blocks are truncated).
+const PrettyMaxLinesDefault = 40
+
+// prettyBlock is an attribute rendered as an indented multi-line block (under the record).
+type prettyBlock struct {
+	key   string
+	sep   string // "=" (or ":" for groups with GroupLayoutTree)
+	lines []string
+}
+
+// prettyJSON returns the JSON representation of the value if it should be rendered as a pretty block
+// (structs, maps, slices and arrays or strings containing a JSON object or array).
+func prettyJSON(value slog.Value) ([]byte, bool) {
+	value = value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		s := strings.TrimSpace(value.String())
+		if len(s) >= 2 && (s[0] == '{' || s[0] == '[') && json.Valid([]byte(s)) {
+			return []byte(s), true
+		}
+	case slog.KindAny:
+		v := value.Any()
+		switch v.(type) {
+		case error, fmt.Stringer, *slog.Source:
+			return nil, false
+		}
+		rv := reflect.ValueOf(v)
+		for rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				return nil, false
+			}
+			rv = rv.Elem()
+		}
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			if rv.Type().Elem().Kind() == reflect.Uint8 {
+				return nil, false
+			}
+		case reflect.Struct:
+			if isOpaqueStruct(v, rv.Type()) {
+				return nil, false
+			}
+		case reflect.Map:
+		default:
+			return nil, false
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, false
+		}
+		return b, true
+	}
+	return nil, false
+}
+
+// opaqueStruct returns the value if it is a struct (or a pointer to a struct) which would be marshaled to an
+// empty JSON object (no exported field and no MarshalJSON/MarshalText method).
+func opaqueStruct(value slog.Value) (any, bool) {
+	value = value.Resolve()
+	if value.Kind() != slog.KindAny {
+		return nil, false
+	}
+	v := value.Any()
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct || !isOpaqueStruct(v, rv.Type()) {
+		return nil, false
+	}
+	return v, true
+}
+
+func isOpaqueStruct(v any, t reflect.Type) bool {
+	switch v.(type) {
+	case json.Marshaler, encoding.TextMarshaler:
+		return false
+	}
+	return !hasExportedFields(t)
+}
+
+// hasExportedFields returns true if the struct type has exported fields (including the promoted ones of embedded structs).
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() {
+			return true
+		}
+		if field.Anonymous {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && hasExportedFields(ft) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// prettyFrame is an object or an array being rendered.
+type prettyFrame struct {
+	object       bool
+	n            int
+	expectingKey bool
+}
+
+// prettyPrinter renders JSON documents as indented lines (with the colors of the theme if not nil).
+type prettyPrinter struct {
+	theme    *Theme
+	maxDepth int
+	lines    []string
+	line     strings.Builder
+	stack    []prettyFrame
+}
+
+func (pp *prettyPrinter) newLine() {
+	pp.lines = append(pp.lines, pp.line.String())
+	pp.line.Reset()
+	pp.line.WriteString(strings.Repeat("  ", len(pp.stack)))
+}
+
+func (pp *prettyPrinter) write(style string, s string) {
+	if pp.theme != nil && style != "" {
+		pp.line.WriteString(style)
+		pp.line.WriteString(s)
+		pp.line.WriteString(ansi.Reset)
+		return
+	}
+	pp.line.WriteString(s)
+}
+
+func quoteJSON(s string) string {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(s); err != nil {
+		return strconv.Quote(s)
+	}
+	// the JSON encoder doesn't escape all the unsafe runes (DEL, C1 controls, bidirectional formatting characters)
+	var sb strings.Builder
+	for _, r := range strings.TrimSuffix(buffer.String(), "\n") {
+		if isUnsafeRune(r) {
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// render returns the lines of the given JSON document (ok is false if the document is invalid).
+func (pp *prettyPrinter) render(data []byte) (lines []string, ok bool) {
+	var valueStyle, keyStyle string
+	if pp.theme != nil {
+		valueStyle, keyStyle = pp.theme.Value, pp.theme.Key
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false
+		}
+		var top *prettyFrame
+		if len(pp.stack) > 0 {
+			top = &pp.stack[len(pp.stack)-1]
+		}
+		if delim, isDelim := token.(json.Delim); isDelim && (delim == '}' || delim == ']') {
+			n := top.n
+			pp.stack = pp.stack[:len(pp.stack)-1]
+			if n > 0 {
+				pp.newLine()
+			}
+			pp.line.WriteString(delim.String())
+			pp.valueDone()
+			continue
+		}
+		if top != nil && top.object && top.expectingKey {
+			if top.n > 0 {
+				pp.line.WriteString(",")
+			}
+			pp.newLine()
+			pp.write(keyStyle, quoteJSON(token.(string)))
+			pp.line.WriteString(": ")
+			top.n++
+			top.expectingKey = false
+			continue
+		}
+		if top != nil && !top.object {
+			if top.n > 0 {
+				pp.line.WriteString(",")
+			}
+			pp.newLine()
+			top.n++
+		}
+		switch t := token.(type) {
+		case json.Delim:
+			if len(pp.stack) >= pp.maxDepth {
+				// skip the nested object/array
+				skipped := -1
+				for depth := 1; depth > 0; skipped++ {
+					next, err := decoder.Token()
+					if err != nil {
+						return nil, false
+					}
+					if d, ok := next.(json.Delim); ok {
+						if d == '{' || d == '[' {
+							depth++
+						} else {
+							depth--
+						}
+					}
+				}
+				switch {
+				case skipped == 0 && t == '{':
+					pp.line.WriteString("{}")
+				case skipped == 0:
+					pp.line.WriteString("[]")
+				case t == '{':
+					pp.line.WriteString("{…}")
+				default:
+					pp.line.WriteString("[…]")
+				}
+				pp.valueDone()
+				continue
+			}
+			pp.line.WriteString(t.String())
+			pp.stack = append(pp.stack, prettyFrame{object: t == '{', expectingKey: t == '{'})
+		case string:
+			pp.write(valueStyle, quoteJSON(t))
+			pp.valueDone()
+		case json.Number:
+			pp.write(valueStyle, t.String())
+			pp.valueDone()
+		case bool:
+			pp.write(valueStyle, strconv.FormatBool(t))
+			pp.valueDone()
+		case nil:
+			pp.write(valueStyle, "null")
+			pp.valueDone()
+		}
+	}
+	pp.lines = append(pp.lines, pp.line.String())
+	return pp.lines, true
+}
+
+// valueDone must be called after a complete value (to expect the next key in an object).
+func (pp *prettyPrinter) valueDone() {
+	if len(pp.stack) > 0 && pp.stack[len(pp.stack)-1].object {
+		pp.stack[len(pp.stack)-1].expectingKey = true
+	}
+}
+
+// renderPretty returns the lines of the pretty representation of the value (ok is false if the value
+// is not a pretty value).
+func (p *printer) renderPretty(value slog.Value, theme *Theme) (lines []string, ok bool) {
+	data, ok := prettyJSON(value)
+	if !ok {
+		return nil, false
+	}
+	pp := &prettyPrinter{theme: theme, maxDepth: p.opts.PrettyMaxDepth}
+	lines, ok = pp.render(data)
+	if !ok {
+		return nil, false
+	}
+	return p.truncateLines(lines), true
+}
+
+// truncateLines truncates the lines of a block to PrettyMaxLines.
+func (p *printer) truncateLines(lines []string) []string {
+	if len(lines) > p.opts.PrettyMaxLines {
+		more := len(lines) - p.opts.PrettyMaxLines
+		lines = append(lines[:p.opts.PrettyMaxLines], fmt.Sprintf("… (%d more lines)", more))
+	}
+	return lines
+}
+
+// inlineAttr is an attribute rendered inline.
+type inlineAttr struct {
+	external.StringifiedAttr
+	style  string // The style of the value ("" means the default one).
+	group  bool   // If true, the attribute is a compact group (rendered without "=").
+	styled string // The value rendered with colors (for compact groups).
+}
+
+// splitAttr returns the attribute rendered inline or as a block (pretty values, multi-line strings and rich errors),
+// keys and values are sanitized.
+func (p *printer) splitAttr(attr external.FlattenedAttr, theme *Theme) (inlineAttr, *prettyBlock) {
+	key := p.sanitizeKey(attr.Key)
+	if err, ok := attr.Value.Resolve().Any().(error); ok && attr.Value.Kind() == slog.KindAny && err != nil && *p.opts.RichErrors {
+		lines := p.truncateLines(renderError(err, theme, p.opts.Glyphs, p.sanitizeText))
+		if len(lines) == 1 {
+			return inlineAttr{StringifiedAttr: external.StringifiedAttr{Key: key, Value: p.sanitizeValue(err.Error())}, style: theme.errorValue()}, nil
+		}
+		return inlineAttr{}, &prettyBlock{key: key, sep: "=", lines: lines}
+	}
+	if *p.opts.PrettyValues {
+		if lines, ok := p.renderPretty(attr.Value, theme); ok {
+			if len(lines) == 1 {
+				// empty (or elided) object/array
+				return inlineAttr{StringifiedAttr: external.StringifiedAttr{Key: key, Value: lines[0]}}, nil
+			}
+			return inlineAttr{}, &prettyBlock{key: key, sep: "=", lines: lines}
+		}
+		if v, ok := opaqueStruct(attr.Value); ok {
+			// the JSON representation would be an empty object
+			return inlineAttr{StringifiedAttr: external.StringifiedAttr{Key: key, Value: p.sanitizeValue(fmt.Sprintf("%+v", v))}}, nil
+		}
+	}
+	if *p.opts.PrettyValues || isStackTraceKey(attr.Key) {
+		if value := attr.Value.Resolve(); value.Kind() == slog.KindString && strings.Contains(strings.TrimRight(value.String(), "\n"), "\n") {
+			// multi-line strings (stack traces for example), each line is sanitized and indented (so it can't be confused with a record)
+			lines := strings.Split(strings.TrimRight(value.String(), "\n"), "\n")
+			for i, line := range lines {
+				lines[i] = "  " + p.sanitizeText(strings.ReplaceAll(line, "\t", "    "))
+			}
+			return inlineAttr{}, &prettyBlock{key: key, sep: "=", lines: append([]string{""}, p.truncateLines(lines)...)}
+		}
+	}
+	stringified := attr.Stringified()
+	return inlineAttr{StringifiedAttr: external.StringifiedAttr{Key: key, Value: p.sanitizeValue(stringified.Value)}}, nil
+}
+
+// isStackTraceKey returns true if the (flattened) key is the one of the stack traces added by the stacktrace
+// handler (ModeAddAttr) or by slogc (panics and crashes), their multi-line values are always rendered as blocks.
+func isStackTraceKey(key string) bool {
+	return key == stacktrace.KeyNameForModeAddAttrDefault || strings.HasSuffix(key, "."+stacktrace.KeyNameForModeAddAttrDefault)
+}
+
+// splitFlattened splits the flattened attributes between the ones rendered inline and the ones rendered as blocks.
+func (p *printer) splitFlattened(attrs []external.FlattenedAttr, theme *Theme) ([]inlineAttr, []prettyBlock) {
+	inline := make([]inlineAttr, 0, len(attrs))
+	var blocks []prettyBlock
+	for _, attr := range attrs {
+		inlined, block := p.splitAttr(attr, theme)
+		if block != nil {
+			blocks = append(blocks, *block)
+		} else {
+			inline = append(inline, inlined)
+		}
+	}
+	return inline, blocks
+}
+
+// writePrettyBlocks writes the blocks (one "key=" line with the beginning of the value and then indented lines).
+func writePrettyBlocks(buffer *bytes.Buffer, blocks []prettyBlock, indent string, theme *Theme) {
+	for _, block := range blocks {
+		buffer.WriteString("\n")
+		buffer.WriteString(indent)
+		if theme != nil {
+			buffer.WriteString(theme.Key)
+			buffer.WriteString(block.key)
+			buffer.WriteString(ansi.Reset)
+			buffer.WriteString(theme.Equal)
+			buffer.WriteString(block.sep)
+			buffer.WriteString(ansi.Reset)
+		} else {
+			buffer.WriteString(block.key)
+			buffer.WriteString(block.sep)
+		}
+		for i, line := range block.lines {
+			if i > 0 {
+				buffer.WriteString("\n")
+				buffer.WriteString(indent)
+			}
+			buffer.WriteString(line)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package human
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/fabien-marty/slog-helpers/internal/ansi"
+	"github.com/stretchr/testify/assert"
+)
+
+type prettyUser struct {
+	Name  string   `json:"name"`
+	Age   int      `json:"age"`
+	Tags  []string `json:"tags"`
+	Empty []int    `json:"empty"`
+}
+
+func TestPrettyJSON(t *testing.T) {
+	_, ok := prettyJSON(slog.StringValue("foo"))
+	assert.False(t, ok)
+	_, ok = prettyJSON(slog.StringValue("{invalid"))
+	assert.False(t, ok)
+	_, ok = prettyJSON(slog.AnyValue([]byte("foo")))
+	assert.False(t, ok)
+	_, ok = prettyJSON(slog.AnyValue(assert.AnError))
+	assert.False(t, ok)
+	_, ok = prettyJSON(slog.AnyValue(make(chan int)))
+	assert.False(t, ok)
+	data, ok := prettyJSON(slog.StringValue(` {"foo": 1} `))
+	assert.True(t, ok)
+	assert.Equal(t, `{"foo": 1}`, string(data))
+	data, ok = prettyJSON(slog.AnyValue(&prettyUser{Name: "bob"}))
+	assert.True(t, ok)
+	assert.Equal(t, `{"name":"bob","age":0,"tags":null,"empty":null}`, string(data))
+}
+
+func TestPrettyPrinter(t *testing.T) {
+	pp := &prettyPrinter{maxDepth: 2}
+	lines, ok := pp.render([]byte(`{"b": 1, "a": [true, null, "x<y"], "c": {"d": {"e": 1}, "f": []}, "g": {}}`))
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		`{`,
+		`  "b": 1,`,
+		`  "a": [`,
+		`    true,`,
+		`    null,`,
+		`    "x<y"`,
+		`  ],`,
+		`  "c": {`,
+		`    "d": {…},`,
+		`    "f": []`,
+		`  },`,
+		`  "g": {}`,
+		`}`,
+	}, lines)
+	assert.Equal(t, `"a\u007f\u202eb\u001b"`, quoteJSON("a\x7f\u202eb\x1b"))
+	pp = &prettyPrinter{maxDepth: 2, theme: &ThemeDefault}
+	lines, ok = pp.render([]byte(`{"a": "b"}`))
+	assert.True(t, ok)
+	assert.Equal(t, `  `+ansi.Yellow+`"a"`+ansi.Reset+`: `+ansi.Magenta+`"b"`+ansi.Reset, lines[1])
+}
+
+func TestHumanHandlerPrettyValues(t *testing.T) {
+	buffer := &strings.Builder{}
+	prettyValues := true
+	h := New(buffer, &Options{
+		TimeFormat:     "-",
+		PrettyValues:   &prettyValues,
+		PrettyMaxLines: 4,
+	})
+	slog.New(h).Info("hello", slog.String("foo", "bar"), slog.Any("user", prettyUser{Name: "bob", Age: 42}), slog.Any("empty", map[string]int{}))
+	assert.Equal(t, `- [INFO ] hello {foo=bar empty={}}
+    user={
+      "name": "bob",
+      "age": 42,
+      "tags": null,
+    … (2 more lines)
+`, buffer.String())
+}
+
+type opaqueID struct {
+	id   int
+	kind string
+}
+
+type embeddingUser struct {
+	prettyUser
+}
+
+func TestHumanHandlerPrettyValuesOpaqueStructs(t *testing.T) {
+	_, ok := prettyJSON(slog.AnyValue(opaqueID{id: 1, kind: "user"}))
+	assert.False(t, ok)
+	_, ok = prettyJSON(slog.AnyValue(embeddingUser{prettyUser{Name: "bob"}})) // promoted exported fields
+	assert.True(t, ok)
+	buffer := &strings.Builder{}
+	prettyValues := true
+	h := New(buffer, &Options{
+		TimeFormat:   "-",
+		PrettyValues: &prettyValues,
+	})
+	slog.New(h).Info("hello", slog.Any("id", opaqueID{id: 1, kind: "user"}), slog.Any("ptr", &opaqueID{id: 2}))
+	assert.Equal(t, "- [INFO ] hello {id=\"{id:1 kind:user}\" ptr=\"&{id:2 kind:}\"}\n", buffer.String())
+}
+
+func TestHumanHandlerPrettyValuesDisabledWithoutColors(t *testing.T) {
+	buffer := &strings.Builder{}
+	h := New(buffer, &Options{
+		TimeFormat: "-",
+	})
+	slog.New(h).Info("hello", slog.String("json", `{"a": 1, "b": 2}`))
+	assert.Equal(t, "- [INFO ] hello {json=\"{\\\"a\\\": 1, \\\"b\\\": 2}\"}\n", buffer.String())
+}
@@ -0,0 +1,126 @@
+package human
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/fabien-marty/slog-helpers/internal/ansi"
+)
+
+// SourcePlacement is an enumeration type that defines where the source location is rendered (when AddSource is set).
+type SourcePlacement string
+
+// SourcePlacementPrefix renders the source location before the message.
+const SourcePlacementPrefix SourcePlacement = "prefix"
+
+// SourcePlacementSuffix renders the source location after the message.
+const SourcePlacementSuffix SourcePlacement = "suffix"
+
+// SourcePlacementLine renders the source location on a (dim) second line.
+const SourcePlacementLine SourcePlacement = "line"
+
+// SourcePlacementDefault is the default source placement.
+const SourcePlacementDefault = SourcePlacementSuffix
+
+// moduleRoots caches the module root (directory with a go.mod file, "" if not found) of source directories.
+var moduleRoots sync.Map
+
+func findModuleRoot(dir string) string {
+	if root, ok := moduleRoots.Load(dir); ok {
+		return root.(string)
+	}
+	root := ""
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			root = d
+			break
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	moduleRoots.Store(dir, root)
+	return root
+}
+
+func gopaths() []string {
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		gopath = filepath.Join(home, "go")
+	}
+	return filepath.SplitList(gopath)
+}
+
+// shortenPath returns the path of a source file relative to the module cache, GOROOT, its module root or GOPATH
+// (or the last directory and the file name if no root is found).
+func shortenPath(path string) string {
+	if !filepath.IsAbs(path) {
+		return path // -trimpath build
+	}
+	slashPath := filepath.ToSlash(path)
+	if _, after, found := strings.Cut(slashPath, "/pkg/mod/"); found {
+		return after // module cache (GOPATH/pkg/mod)
+	}
+	if goroot := runtime.GOROOT(); goroot != "" {
+		if after, found := strings.CutPrefix(slashPath, filepath.ToSlash(goroot)+"/src/"); found {
+			return after
+		}
+	}
+	if root := findModuleRoot(filepath.Dir(path)); root != "" {
+		if rel, err := filepath.Rel(root, path); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	for _, gopath := range gopaths() {
+		if after, found := strings.CutPrefix(slashPath, filepath.ToSlash(gopath)+"/src/"); found {
+			return after
+		}
+	}
+	return filepath.ToSlash(filepath.Join(filepath.Base(filepath.Dir(path)), filepath.Base(path)))
+}
+
+// shortenFunction returns the function name without its package path (for example "(*Handler).Handle").
+func shortenFunction(function string) string {
+	function = function[strings.LastIndex(function, "/")+1:]
+	if _, after, found := strings.Cut(function, "."); found {
+		return after
+	}
+	return function
+}
+
+// formatSource renders a source location as "pkg/file.go:42 (func)".
+func formatSource(source *slog.Source) string {
+	res := fmt.Sprintf("%s:%d", shortenPath(source.File), source.Line)
+	if source.Function != "" {
+		res += " (" + shortenFunction(source.Function) + ")"
+	}
+	return res
+}
+
+// formatSourceColor renders a source location like formatSource (as a hyperlink if enabled).
+func (p *printer) formatSourceColor(source *slog.Source) string {
+	if !p.hyperlinks {
+		return formatSource(source)
+	}
+	return ansi.Hyperlink(ansi.EditorURL(p.opts.EditorURLTemplate, source.File, source.Line), formatSource(source))
+}
+
+// splitSource extracts the source location (added by external.Handler when AddSource is set) from the attributes
+// and returns it with the other attributes.
+func splitSource(attrs []slog.Attr) (*slog.Source, []slog.Attr) {
+	if len(attrs) > 0 && attrs[0].Key == slog.SourceKey && attrs[0].Value.Kind() == slog.KindAny {
+		if s, ok := attrs[0].Value.Any().(*slog.Source); ok {
+			return s, attrs[1:]
+		}
+	}
+	return nil, attrs
+}
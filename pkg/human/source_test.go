@@ -0,0 +1,86 @@
+package human
+
+import (
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/fabien-marty/slog-helpers/internal/ansi"
+	"github.com/fabien-marty/slog-helpers/internal/bufferpool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShortenPath(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	assert.Equal(t, "pkg/human/source_test.go", shortenPath(file))
+	assert.Equal(t, "github.com/foo/bar@v1.2.3/baz.go", shortenPath("/home/foo/go/pkg/mod/github.com/foo/bar@v1.2.3/baz.go"))
+	assert.Equal(t, "log/slog/logger.go", shortenPath(filepath.Join(runtime.GOROOT(), "src", "log", "slog", "logger.go")))
+	assert.Equal(t, "github.com/foo/bar/baz.go", shortenPath("github.com/foo/bar/baz.go"))
+	tmp := t.TempDir()
+	t.Setenv("GOPATH", tmp)
+	assert.Equal(t, "github.com/foo/bar/baz.go", shortenPath(filepath.Join(tmp, "src", "github.com", "foo", "bar", "baz.go")))
+	assert.Equal(t, "bar/baz.go", shortenPath(filepath.Join(tmp, "foo", "bar", "baz.go")))
+}
+
+func TestShortenFunction(t *testing.T) {
+	assert.Equal(t, "main", shortenFunction("main.main"))
+	assert.Equal(t, "(*Handler).Handle", shortenFunction("github.com/foo/bar.(*Handler).Handle"))
+	assert.Equal(t, "Foo.func1", shortenFunction("github.com/foo/bar.Foo.func1"))
+}
+
+func TestHumanHandlerSource(t *testing.T) {
+	for placement, expected := range map[SourcePlacement]string{
+		SourcePlacementPrefix: "[INFO ] pkg/human/source_test.go:xx (TestHumanHandlerSource) hello {foo=bar}\n",
+		SourcePlacementSuffix: "[INFO ] hello pkg/human/source_test.go:xx (TestHumanHandlerSource) {foo=bar}\n",
+		SourcePlacementLine:   "[INFO ] hello {foo=bar}\n    @ pkg/human/source_test.go:xx (TestHumanHandlerSource)\n",
+	} {
+		buffer := bufferpool.Get()
+		h := New(buffer, &Options{
+			HandlerOptions:  slog.HandlerOptions{AddSource: true},
+			SourcePlacement: placement,
+		})
+		slog.New(h).Info("hello", slog.String("foo", "bar"))
+		_, after, _ := strings.Cut(buffer.String(), " ")
+		assert.Equal(t, expected, replaceDigits(after), placement)
+		bufferpool.Put(buffer)
+	}
+}
+
+func TestHumanHandlerSourceColors(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	h := New(buffer, &Options{
+		HandlerOptions: slog.HandlerOptions{AddSource: true},
+		UseColors:      true,
+	})
+	slog.New(h).Info("hello")
+	assert.Contains(t, buffer.String(), ansi.Bold+"hello"+ansi.Reset+" "+ansi.Dim+"pkg/human/source_test.go:")
+	assert.True(t, strings.HasSuffix(buffer.String(), "(TestHumanHandlerSourceColors)"+ansi.Reset+"\n"))
+}
+
+func TestHumanHandlerHyperlinks(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	hyperlinks := true
+	h := New(buffer, &Options{
+		HandlerOptions:    slog.HandlerOptions{AddSource: true},
+		UseColors:         true,
+		Hyperlinks:        &hyperlinks,
+		EditorURLTemplate: EditorURLTemplateIdea,
+	})
+	slog.New(h).Info("hello", slog.String("url", "https://example.com"), slog.String("foo", "bar"))
+	_, file, _, _ := runtime.Caller(0)
+	assert.Contains(t, buffer.String(), ansi.Dim+"\x1b]8;;idea://open?file="+file+"&line=")
+	assert.Contains(t, buffer.String(), ansi.Magenta+"\x1b]8;;https://example.com\x1b\\https://example.com\x1b]8;;\x1b\\"+ansi.Reset)
+	assert.Contains(t, buffer.String(), ansi.Magenta+"bar"+ansi.Reset)
+	// no hyperlinks without colors
+	buffer.Reset()
+	h = New(buffer, &Options{
+		HandlerOptions: slog.HandlerOptions{AddSource: true},
+		Hyperlinks:     &hyperlinks,
+	})
+	slog.New(h).Info("hello", slog.String("url", "https://example.com"))
+	assert.NotContains(t, buffer.String(), "\x1b")
+}
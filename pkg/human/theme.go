@@ -0,0 +1,241 @@
+package human
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/fabien-marty/slog-helpers/internal/ansi"
+)
+
+// Theme defines the ANSI escape sequences used to render records when colors are enabled.
+//
+// Any escape sequence can be used (see ansi.Color256 or ansi.RGB helpers for 256-color and truecolor palettes), an empty
+// string means "no style".
+type Theme struct {
+	Name       string
+	Time       string
+	Message    string
+	Key        string // Attribute keys.
+	Equal      string // The "=" between attribute keys and values.
+	Value      string // Attribute values.
+	ErrorValue string // Error attribute values.
+	Source     string // Source locations (and other secondary information like error types).
+	Debug      string
+	Info       string
+	Warn       string
+	Error      string
+	Fatal      string
+	Unknown    string // Other levels.
+}
+
+// ThemeDefault is the default theme (16 colors).
+var ThemeDefault = Theme{
+	Name:       "default",
+	Time:       ansi.Cyan,
+	Message:    ansi.Bold,
+	Key:        ansi.Yellow,
+	Equal:      ansi.Bold,
+	Value:      ansi.Magenta,
+	ErrorValue: ansi.Red,
+	Source:     ansi.Dim,
+	Debug:      ansi.Green,
+	Info:       ansi.Blue,
+	Warn:       ansi.Red,
+	Error:      ansi.RedBackground + ansi.White,
+	Fatal:      ansi.RedBackground + ansi.White + ansi.Bold,
+	Unknown:    ansi.Cyan,
+}
+
+// ThemeSolarizedDark is a theme for terminals with a solarized dark background (truecolor).
+var ThemeSolarizedDark = Theme{
+	Name:       "solarized-dark",
+	Time:       ansi.RGB(0x2a, 0xa1, 0x98),
+	Message:    ansi.Bold + ansi.RGB(0x93, 0xa1, 0xa1),
+	Key:        ansi.RGB(0xb5, 0x89, 0x00),
+	Equal:      ansi.RGB(0x58, 0x6e, 0x75),
+	Value:      ansi.RGB(0x6c, 0x71, 0xc4),
+	ErrorValue: ansi.RGB(0xdc, 0x32, 0x2f),
+	Source:     ansi.RGB(0x58, 0x6e, 0x75),
+	Debug:      ansi.RGB(0x85, 0x99, 0x00),
+	Info:       ansi.RGB(0x26, 0x8b, 0xd2),
+	Warn:       ansi.RGB(0xcb, 0x4b, 0x16),
+	Error:      ansi.BgRGB(0xdc, 0x32, 0x2f) + ansi.RGB(0xfd, 0xf6, 0xe3),
+	Fatal:      ansi.BgRGB(0xd3, 0x36, 0x82) + ansi.RGB(0xfd, 0xf6, 0xe3) + ansi.Bold,
+	Unknown:    ansi.RGB(0x2a, 0xa1, 0x98),
+}
+
+// ThemeSolarizedLight is a theme for terminals with a solarized light (or any light) background (truecolor).
+var ThemeSolarizedLight = Theme{
+	Name:       "solarized-light",
+	Time:       ansi.RGB(0x2a, 0xa1, 0x98),
+	Message:    ansi.Bold + ansi.RGB(0x58, 0x6e, 0x75),
+	Key:        ansi.RGB(0xb5, 0x89, 0x00),
+	Equal:      ansi.RGB(0x93, 0xa1, 0xa1),
+	Value:      ansi.RGB(0x6c, 0x71, 0xc4),
+	ErrorValue: ansi.RGB(0xdc, 0x32, 0x2f),
+	Source:     ansi.RGB(0x93, 0xa1, 0xa1),
+	Debug:      ansi.RGB(0x85, 0x99, 0x00),
+	Info:       ansi.RGB(0x26, 0x8b, 0xd2),
+	Warn:       ansi.RGB(0xcb, 0x4b, 0x16),
+	Error:      ansi.BgRGB(0xdc, 0x32, 0x2f) + ansi.RGB(0xfd, 0xf6, 0xe3),
+	Fatal:      ansi.BgRGB(0xd3, 0x36, 0x82) + ansi.RGB(0xfd, 0xf6, 0xe3) + ansi.Bold,
+	Unknown:    ansi.RGB(0x2a, 0xa1, 0x98),
+}
+
+// ThemeHighContrast is a theme with bright colors for dark backgrounds (256 colors).
+var ThemeHighContrast = Theme{
+	Name:       "high-contrast",
+	Time:       ansi.Color256(51),
+	Message:    ansi.Bold + ansi.Color256(231),
+	Key:        ansi.Color256(226),
+	Equal:      ansi.Bold + ansi.Color256(231),
+	Value:      ansi.Color256(213),
+	ErrorValue: ansi.Bold + ansi.Color256(196),
+	Source:     ansi.Color256(250),
+	Debug:      ansi.Bold + ansi.Color256(46),
+	Info:       ansi.Bold + ansi.Color256(45),
+	Warn:       ansi.Bold + ansi.Color256(214),
+	Error:      ansi.BgColor256(196) + ansi.Color256(231) + ansi.Bold,
+	Fatal:      ansi.BgColor256(196) + ansi.Color256(231) + ansi.Bold + ansi.Underline,
+	Unknown:    ansi.Bold + ansi.Color256(51),
+}
+
+// ThemeMonochromeBold is a theme without any color (only bold, dim, underline and reverse video styles).
+var ThemeMonochromeBold = Theme{
+	Name:       "monochrome-bold",
+	Message:    ansi.Bold,
+	Key:        ansi.Underline,
+	ErrorValue: ansi.Bold + ansi.Underline,
+	Source:     ansi.Dim,
+	Debug:      ansi.Dim,
+	Warn:       ansi.Bold,
+	Error:      ansi.Reverse,
+	Fatal:      ansi.Reverse + ansi.Bold,
+}
+
+// Themes is the list of built-in themes.
+var Themes = []*Theme{&ThemeDefault, &ThemeSolarizedDark, &ThemeSolarizedLight, &ThemeHighContrast, &ThemeMonochromeBold}
+
+// GetTheme returns the built-in theme with the given name (case insensitive) or nil if not found.
+func GetTheme(name string) *Theme {
+	for _, theme := range Themes {
+		if strings.EqualFold(theme.Name, name) {
+			return theme
+		}
+	}
+	return nil
+}
+
+func (t *Theme) level(level slog.Level) string {
+	switch level {
+	case slog.LevelDebug:
+		return t.Debug
+	case slog.LevelInfo:
+		return t.Info
+	case slog.LevelWarn:
+		return t.Warn
+	case slog.LevelError:
+		return t.Error
+	case LevelFatal:
+		return t.Fatal
+	}
+	return t.Unknown
+}
+
+// errorValue returns the style of error values (the theme can be nil).
+func (t *Theme) errorValue() string {
+	if t == nil {
+		return ""
+	}
+	return t.ErrorValue
+}
+
+// Glyphs defines the decorative characters used to render records.
+type Glyphs struct {
+	Name   string
+	Record string // The prefix of each record (only with colors).
+	Attrs  string // The prefix of the attributes line (only with colors).
+	Source string // The prefix of the source line (with SourcePlacementLine).
+
+	TreeBranch string // The branch to a child of a tree (which is not the last one).
+	TreeLast   string // The branch to the last child of a tree.
+	TreeLine   string // The vertical line under a branch (must have the same width than the branches).
+
+	Debug   string // Level icons are rendered before level labels (can be empty).
+	Info    string
+	Warn    string
+	Error   string
+	Fatal   string
+	Unknown string
+}
+
+// GlyphsASCII is a glyph set with ASCII characters only.
+var GlyphsASCII = Glyphs{
+	Name:   "ascii",
+	Record: "> ",
+	Attrs:  "-> ",
+	Source: "@ ",
+
+	TreeBranch: "|- ",
+	TreeLast:   "`- ",
+	TreeLine:   "|  ",
+}
+
+// GlyphsUnicode is the default glyph set (with unicode arrows).
+var GlyphsUnicode = Glyphs{
+	Name:   "unicode",
+	Record: "▶ ",
+	Attrs:  "↳ ",
+	Source: "@ ",
+
+	TreeBranch: "├─ ",
+	TreeLast:   "└─ ",
+	TreeLine:   "│  ",
+}
+
+// GlyphsEmoji is a glyph set with unicode arrows and emoji level icons.
+var GlyphsEmoji = Glyphs{
+	Name:   "emoji",
+	Attrs:  "↳ ",
+	Source: "📍 ",
+
+	TreeBranch: "├─ ",
+	TreeLast:   "└─ ",
+	TreeLine:   "│  ",
+
+	Debug:   "🐛 ",
+	Info:    "💬 ",
+	Warn:    "🔶 ",
+	Error:   "🔴 ",
+	Fatal:   "💀 ",
+	Unknown: "❔ ",
+}
+
+// GlyphSets is the list of built-in glyph sets.
+var GlyphSets = []*Glyphs{&GlyphsASCII, &GlyphsUnicode, &GlyphsEmoji}
+
+// GetGlyphs returns the built-in glyph set with the given name (case insensitive) or nil if not found.
+func GetGlyphs(name string) *Glyphs {
+	for _, glyphs := range GlyphSets {
+		if strings.EqualFold(glyphs.Name, name) {
+			return glyphs
+		}
+	}
+	return nil
+}
+
+func (g *Glyphs) level(level slog.Level) string {
+	switch level {
+	case slog.LevelDebug:
+		return g.Debug
+	case slog.LevelInfo:
+		return g.Info
+	case slog.LevelWarn:
+		return g.Warn
+	case slog.LevelError:
+		return g.Error
+	case LevelFatal:
+		return g.Fatal
+	}
+	return g.Unknown
+}
@@ -0,0 +1,53 @@
+package human
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/fabien-marty/slog-helpers/internal/ansi"
+	"github.com/fabien-marty/slog-helpers/internal/bufferpool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTheme(t *testing.T) {
+	assert.Equal(t, &ThemeSolarizedLight, GetTheme("Solarized-Light"))
+	assert.Nil(t, GetTheme("foo"))
+	for _, theme := range Themes {
+		assert.Equal(t, theme, GetTheme(theme.Name))
+	}
+	assert.Equal(t, &GlyphsEmoji, GetGlyphs("EMOJI"))
+	assert.Nil(t, GetGlyphs("foo"))
+}
+
+func TestHumanHandlerTheme(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	h := New(buffer, &Options{
+		HandlerOptions: slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		},
+		UseColors: true,
+		Theme:     &ThemeMonochromeBold,
+		Glyphs:    &GlyphsASCII,
+	})
+	slog.New(h).Warn("hello", slog.String("foo", "bar"))
+	expected := "> " + "                    " + ansi.Reset + " " + ansi.Bold + "[WARN ]" + ansi.Reset + " " + ansi.Bold + "hello" + ansi.Reset +
+		"\n    -> " + ansi.Underline + "foo" + ansi.Reset + "=" + ansi.Reset + "bar" + ansi.Reset + "\n"
+	assert.Equal(t, expected, buffer.String())
+}
+
+func TestHumanHandlerGlyphsEmoji(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	h := New(buffer, &Options{
+		TimeFormat: "-",
+		Glyphs:     &GlyphsEmoji,
+	})
+	slog.New(h).Error("hello")
+	assert.Equal(t, "- 🔴 [ERROR] hello\n", buffer.String())
+}
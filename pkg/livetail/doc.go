@@ -0,0 +1,23 @@
+// livetail.Handler is a slog handler that streams log records to HTTP clients as Server-Sent Events.
+//
+// It decorates another slog.Handler given in the New() method: records enabled by the original handler
+// are forwarded as usual (the main output is not modified) and the Handler is also an http.Handler
+// streaming the records to connected clients as they are logged.
+//
+// Clients can filter the records with query parameters:
+//   - level: the minimal level (for example "level=warn", default to DEBUG)
+//   - contains: a substring of the message
+//   - attr.<key>: an attribute value (for example "attr.http.method=GET", keys of grouped attributes are dotted)
+//   - format: "json" for JSON lines, human readable text otherwise
+//
+// Slow clients are dropped (the logger is never blocked).
+//
+// Important: to also stream the records filtered from the main output, the Handler must be the outermost handler.
+//
+// Full example:
+//
+//	tailHandler := New(slog.NewJSONHandler(os.Stderr, nil), &Options{})
+//	logger := slog.New(tailHandler)
+//	http.Handle("/debug/tail", tailHandler)
+//	// curl -N 'http://localhost:8080/debug/tail?level=warn&format=json'
+package livetail
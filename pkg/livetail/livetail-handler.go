@@ -0,0 +1,299 @@
+package livetail
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fabien-marty/slog-helpers/internal/accumulator"
+	"github.com/fabien-marty/slog-helpers/internal/bufferpool"
+	"github.com/fabien-marty/slog-helpers/internal/render"
+)
+
+// ClientBufferSizeDefault is the default number of records buffered per client.
+const ClientBufferSizeDefault = 256
+
+var _ slog.Handler = &Handler{}
+var _ http.Handler = &Handler{}
+
+// Options is a struct that contains the options for the (live tail) Handler.
+type Options struct {
+	ClientBufferSize int // The number of records buffered per client, a client is dropped when its buffer is full (default to ClientBufferSizeDefault).
+}
+
+type filter struct {
+	level    slog.Level
+	contains string
+	attrs    map[string]string
+	json     bool
+}
+
+type client struct {
+	filter  filter
+	records chan slog.Record
+	dropped chan struct{}
+}
+
+// hub is shared between a Handler and all the handlers derived from it (WithAttrs/WithGroup).
+type hub struct {
+	mutex   sync.RWMutex
+	clients map[*client]struct{}
+	count   atomic.Int64 // the number of clients (to return early without locking when nobody is listening)
+}
+
+// Handler is a slog handler that streams log records to HTTP clients as Server-Sent Events.
+type Handler struct {
+	slog.Handler
+	*accumulator.Accumulator
+	opts *Options
+	hub  *hub
+}
+
+// New creates a new Handler.
+func New(originalHandler slog.Handler, options *Options) *Handler {
+	if options.ClientBufferSize <= 0 {
+		options.ClientBufferSize = ClientBufferSizeDefault
+	}
+	return &Handler{
+		Handler:     originalHandler,
+		Accumulator: accumulator.New(),
+		opts:        options,
+		hub: &hub{
+			clients: map[*client]struct{}{},
+		},
+	}
+}
+
+func (lh *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return lh.Handler.Enabled(ctx, level) || lh.hub.wants(level)
+}
+
+func (lh *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{
+		Handler:     lh.Handler.WithGroup(name),
+		Accumulator: lh.Accumulator.WithGroup(name),
+		opts:        lh.opts,
+		hub:         lh.hub,
+	}
+}
+
+func (lh *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{
+		Handler:     lh.Handler.WithAttrs(attrs),
+		Accumulator: lh.Accumulator.WithAttrs(attrs),
+		opts:        lh.opts,
+		hub:         lh.hub,
+	}
+}
+
+// Handle streams the record to the interested clients and forwards it to the original handler (if enabled).
+func (lh *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if lh.hub.wants(record.Level) {
+		streamed := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+		streamed.AddAttrs(lh.Accumulator.AssembleWithRecordAttrs(record)...)
+		lh.hub.broadcast(streamed)
+	}
+	if !lh.Handler.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return lh.Handler.Handle(ctx, record)
+}
+
+// wants returns true if at least one client is interested in records of the given level.
+func (h *hub) wants(level slog.Level) bool {
+	if h.count.Load() == 0 {
+		return false
+	}
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	for c := range h.clients {
+		if level >= c.filter.level {
+			return true
+		}
+	}
+	return false
+}
+
+// broadcast sends the record to the interested clients (under the read lock, so that concurrent records are
+// broadcast in parallel), slow clients are dropped afterwards.
+func (h *hub) broadcast(record slog.Record) {
+	if h.count.Load() == 0 {
+		return
+	}
+	var slow []*client
+	h.mutex.RLock()
+	for c := range h.clients {
+		if !c.filter.match(record) {
+			continue
+		}
+		select {
+		case c.records <- record:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.mutex.RUnlock()
+	if len(slow) == 0 {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for _, c := range slow {
+		if h.remove(c) {
+			close(c.dropped)
+		}
+	}
+}
+
+func (h *hub) subscribe(c *client) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.clients[c] = struct{}{}
+	h.count.Store(int64(len(h.clients)))
+}
+
+func (h *hub) unsubscribe(c *client) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.remove(c)
+}
+
+// remove removes the client and returns true if it was subscribed (and not already removed).
+//
+// The mutex must be held.
+func (h *hub) remove(c *client) bool {
+	if _, ok := h.clients[c]; !ok {
+		return false
+	}
+	delete(h.clients, c)
+	h.count.Store(int64(len(h.clients)))
+	return true
+}
+
+func newFilter(r *http.Request) (filter, error) {
+	query := r.URL.Query()
+	f := filter{
+		level:    slog.LevelDebug,
+		contains: query.Get("contains"),
+		attrs:    map[string]string{},
+		json:     query.Get("format") == "json",
+	}
+	if level := query.Get("level"); level != "" {
+		err := f.level.UnmarshalText([]byte(level))
+		if err != nil {
+			return f, err
+		}
+	}
+	for key, values := range query {
+		if attrKey, found := strings.CutPrefix(key, "attr."); found && len(values) > 0 {
+			f.attrs[attrKey] = values[0]
+		}
+	}
+	return f, nil
+}
+
+func (f filter) match(record slog.Record) bool {
+	if record.Level < f.level {
+		return false
+	}
+	if f.contains != "" && !strings.Contains(record.Message, f.contains) {
+		return false
+	}
+	if len(f.attrs) == 0 {
+		return true
+	}
+	matched := make(map[string]bool, len(f.attrs))
+	record.Attrs(func(attr slog.Attr) bool {
+		f.matchAttr(matched, "", attr)
+		return true
+	})
+	return len(matched) == len(f.attrs)
+}
+
+// matchAttr adds to matched the (distinct) filter keys matched by attr (and by its children if attr is a group).
+func (f filter) matchAttr(matched map[string]bool, prefix string, attr slog.Attr) {
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix = prefix + attr.Key + "."
+		}
+		for _, child := range attr.Value.Group() {
+			f.matchAttr(matched, prefix, child)
+		}
+		return
+	}
+	key := prefix + attr.Key
+	value, ok := f.attrs[key]
+	if ok && value == attr.Value.Resolve().String() {
+		matched[key] = true
+	}
+}
+
+// ServeHTTP streams the records (matching the filters given as query parameters) as Server-Sent Events.
+func (lh *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	f, err := newFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c := &client{
+		filter:  f,
+		records: make(chan slog.Record, lh.opts.ClientBufferSize),
+		dropped: make(chan struct{}),
+	}
+	lh.hub.subscribe(c)
+	defer lh.hub.unsubscribe(c)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(": connected\n\n"))
+	flusher.Flush()
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	handler := render.NewHuman(buffer)
+	if f.json {
+		handler = render.NewJSON(buffer)
+	}
+	for {
+		select {
+		case record := <-c.records:
+			buffer.Reset()
+			err = writeEvent(w, handler, buffer, record)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-c.dropped:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeEvent renders the record with the handler (which writes to buffer) and writes it as a Server-Sent Event.
+func writeEvent(w http.ResponseWriter, handler slog.Handler, buffer *bytes.Buffer, record slog.Record) error {
+	err := handler.Handle(context.Background(), record)
+	if err != nil {
+		return err
+	}
+	event := bufferpool.Get()
+	defer bufferpool.Put(event)
+	for _, line := range strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n") {
+		event.WriteString("data: ")
+		event.WriteString(line)
+		event.WriteString("\n")
+	}
+	event.WriteString("\n")
+	_, err = w.Write(event.Bytes())
+	return err
+}
@@ -0,0 +1,153 @@
+package livetail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func connect(t *testing.T, url string) (*bufio.Reader, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, ": connected\n", line)
+	_, _ = reader.ReadString('\n')
+	return reader, func() {
+		cancel()
+		resp.Body.Close()
+	}
+}
+
+func readEvent(t *testing.T, reader *bufio.Reader) string {
+	lines := []string{}
+	for {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		if line == "\n" {
+			return strings.Join(lines, "\n")
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+}
+
+func TestLiveTailHandler(t *testing.T) {
+	h := New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}), &Options{})
+	server := httptest.NewServer(h)
+	defer server.Close()
+	logger := slog.New(h)
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelDebug))
+	reader, stop := connect(t, server.URL+"?level=info&contains=hello&attr.group.foo=bar&format=json")
+	defer stop()
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelDebug))
+	assert.True(t, logger.Enabled(context.Background(), slog.LevelInfo))
+	logger.Debug("hello debug", slog.Group("group", slog.String("foo", "bar")))
+	logger.Info("bye", slog.Group("group", slog.String("foo", "bar")))
+	logger.Info("hello info", slog.Group("group", slog.String("foo", "baz")))
+	logger.WithGroup("group").Info("hello info", slog.String("foo", "bar"))
+	event := readEvent(t, reader)
+	assert.True(t, strings.HasPrefix(event, "data: {"))
+	assert.Contains(t, event, `"msg":"hello info","group":{"foo":"bar"}}`)
+}
+
+func TestLiveTailHandlerText(t *testing.T) {
+	h := New(slog.NewTextHandler(io.Discard, nil), &Options{})
+	server := httptest.NewServer(h)
+	defer server.Close()
+	reader, stop := connect(t, server.URL)
+	defer stop()
+	slog.New(h).Debug("hello", slog.String("foo", "bar"))
+	event := readEvent(t, reader)
+	assert.True(t, strings.HasPrefix(event, "data: "))
+	assert.Contains(t, event, "[DEBUG] hello pkg/livetail/livetail-handler_test.go:")
+	assert.Contains(t, event, "(TestLiveTailHandlerText) {foo=bar")
+}
+
+func TestLiveTailHandlerSlowClient(t *testing.T) {
+	h := New(slog.NewTextHandler(io.Discard, nil), &Options{ClientBufferSize: 1})
+	c := &client{
+		filter:  filter{level: slog.LevelDebug},
+		records: make(chan slog.Record, 1),
+		dropped: make(chan struct{}),
+	}
+	h.hub.subscribe(c)
+	logger := slog.New(h)
+	logger.Info("first")
+	logger.Info("second") // the client doesn't read => dropped
+	select {
+	case <-c.dropped:
+	case <-time.After(time.Second):
+		assert.Fail(t, "client not dropped")
+	}
+	assert.False(t, h.hub.wants(slog.LevelError))
+	assert.Equal(t, int64(0), h.hub.count.Load())
+}
+
+func TestLiveTailHandlerConcurrentSlowClients(t *testing.T) {
+	h := New(slog.NewTextHandler(io.Discard, nil), &Options{})
+	clients := []*client{}
+	for i := 0; i < 3; i++ {
+		c := &client{
+			filter:  filter{level: slog.LevelDebug},
+			records: make(chan slog.Record, 1),
+			dropped: make(chan struct{}),
+		}
+		h.hub.subscribe(c)
+		clients = append(clients, c)
+	}
+	assert.Equal(t, int64(3), h.hub.count.Load())
+	logger := slog.New(h)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Info("foo") // the clients don't read => dropped (only once)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, c := range clients {
+		select {
+		case <-c.dropped:
+		default:
+			assert.Fail(t, "client not dropped")
+		}
+		h.hub.unsubscribe(c) // no-op
+	}
+	assert.Equal(t, int64(0), h.hub.count.Load())
+}
+
+func TestLiveTailHandlerBadLevel(t *testing.T) {
+	h := New(slog.NewTextHandler(io.Discard, nil), &Options{})
+	server := httptest.NewServer(h)
+	defer server.Close()
+	resp, err := http.Get(server.URL + "?level=foo")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestFilterMatchDistinctKeys(t *testing.T) {
+	f := filter{attrs: map[string]string{"user": "alice", "http.method": "GET"}}
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "foo", 0)
+	record.AddAttrs(slog.String("user", "alice"), slog.String("user", "alice"))
+	assert.False(t, f.match(record)) // the same key twice does not match two filter keys
+	record.AddAttrs(slog.Group("http", slog.String("method", "GET")))
+	assert.True(t, f.match(record))
+}
@@ -0,0 +1,23 @@
+// ringbuffer.Handler is a slog handler that keeps the last log records in memory (at every level).
+//
+// It decorates another slog.Handler given in the New() method: records enabled by the original handler
+// are forwarded as usual but all records with a level >= Level (default to DEBUG) are also kept in memory,
+// even if they are filtered from the main output.
+//
+// The recent records can be read with:
+//   - Records() (as slog.Record)
+//   - Dump() (human readable format)
+//   - the Handler itself which is also an http.Handler (JSON lines with ?format=json, human readable format otherwise)
+//   - DumpOnPanic() (to use with defer) and DumpOnSignal() (SIGUSR1 by default on unix)
+//
+// Important: to also keep the records filtered from the main output, the Handler must be the outermost handler.
+//
+// Full example:
+//
+//	handler := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})
+//	ringHandler := New(handler, &Options{Size: 500})
+//	logger := slog.New(ringHandler)
+//	defer ringHandler.DumpOnPanic(os.Stderr)
+//	http.Handle("/debug/logs", ringHandler)
+//	logger.Debug("not written but kept in memory")
+package ringbuffer
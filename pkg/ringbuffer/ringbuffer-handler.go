@@ -0,0 +1,225 @@
+package ringbuffer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/fabien-marty/slog-helpers/internal/accumulator"
+	"github.com/fabien-marty/slog-helpers/internal/render"
+)
+
+// SizeDefault is the default number of records kept in memory.
+const SizeDefault = 1000
+
+// LevelDefault is the default minimal level of records kept in memory.
+const LevelDefault = slog.LevelDebug
+
+var _ slog.Handler = &Handler{}
+var _ http.Handler = &Handler{}
+
+// Options is a struct that contains the options for the (ring buffer) Handler.
+type Options struct {
+	Size  int          // The number of records kept in memory (default to SizeDefault).
+	Level slog.Leveler // The minimal level of records kept in memory (default to LevelDefault).
+}
+
+// ring is shared between a Handler and all the handlers derived from it (WithAttrs/WithGroup).
+type ring struct {
+	mutex   sync.Mutex
+	records []slog.Record
+	next    int
+	full    bool
+}
+
+// Handler is a slog handler that keeps the last log records in memory.
+type Handler struct {
+	slog.Handler
+	*accumulator.Accumulator
+	opts *Options
+	ring *ring
+}
+
+// New creates a new Handler.
+func New(originalHandler slog.Handler, options *Options) *Handler {
+	if options.Size <= 0 {
+		options.Size = SizeDefault
+	}
+	if options.Level == nil {
+		options.Level = LevelDefault
+	}
+	return &Handler{
+		Handler:     originalHandler,
+		Accumulator: accumulator.New(),
+		opts:        options,
+		ring: &ring{
+			records: make([]slog.Record, options.Size),
+		},
+	}
+}
+
+func (rh *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= rh.opts.Level.Level() || rh.Handler.Enabled(ctx, level)
+}
+
+func (rh *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{
+		Handler:     rh.Handler.WithGroup(name),
+		Accumulator: rh.Accumulator.WithGroup(name),
+		opts:        rh.opts,
+		ring:        rh.ring,
+	}
+}
+
+func (rh *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{
+		Handler:     rh.Handler.WithAttrs(attrs),
+		Accumulator: rh.Accumulator.WithAttrs(attrs),
+		opts:        rh.opts,
+		ring:        rh.ring,
+	}
+}
+
+// Handle keeps the record in memory (if its level is >= Level) and forwards it to the original handler (if enabled).
+func (rh *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= rh.opts.Level.Level() {
+		kept := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+		kept.AddAttrs(rh.Accumulator.AssembleWithRecordAttrs(record)...)
+		rh.ring.add(kept)
+	}
+	if !rh.Handler.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return rh.Handler.Handle(ctx, record)
+}
+
+func (r *ring) add(record slog.Record) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.records[r.next] = record
+	r.next++
+	if r.next == len(r.records) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// Records returns a copy of the records kept in memory (from the oldest to the newest).
+//
+// Attributes added with WithAttrs/WithGroup are included in the returned records.
+func (rh *Handler) Records() []slog.Record {
+	r := rh.ring
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	res := []slog.Record{}
+	if r.full {
+		res = append(res, r.records[r.next:]...)
+	}
+	return append(res, r.records[:r.next]...)
+}
+
+// Dump writes the records kept in memory (from the oldest to the newest) in a human readable format.
+func (rh *Handler) Dump(w io.Writer) error {
+	handler := render.NewHuman(w)
+	for _, record := range rh.Records() {
+		err := handler.Handle(context.Background(), record)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DumpJSON writes the records kept in memory (from the oldest to the newest) as JSON lines.
+func (rh *Handler) DumpJSON(w io.Writer) error {
+	handler := render.NewJSON(w)
+	for _, record := range rh.Records() {
+		err := handler.Handle(context.Background(), record)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP writes the records kept in memory (from the oldest to the newest).
+//
+// The format is JSON lines with the "format=json" query parameter, human readable text otherwise.
+func (rh *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var err error
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		err = rh.DumpJSON(w)
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		err = rh.Dump(w)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (rh *Handler) dumpWithHeader(w io.Writer, reason string) {
+	fmt.Fprintf(w, "=== dump of the last log records (%s) ===\n", reason)
+	_ = rh.Dump(w)
+	fmt.Fprintf(w, "=== end of the dump ===\n")
+}
+
+// DumpOnPanic dumps the records kept in memory in w (default to stderr) if the program is panicking, then panics again.
+//
+// It must be called with defer (for example at the beginning of the main function):
+//
+//	defer ringHandler.DumpOnPanic(os.Stderr)
+func (rh *Handler) DumpOnPanic(w io.Writer) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if w == nil {
+		w = os.Stderr
+	}
+	rh.dumpWithHeader(w, fmt.Sprintf("panic: %v", r))
+	panic(r)
+}
+
+// DumpOnSignal starts a goroutine that dumps the records kept in memory in w (default to stderr) each time one of the given signals is received.
+//
+// If no signal is given, SIGUSR1 is used on unix (and DumpOnSignal does nothing on other platforms).
+//
+// The returned function stops the signal handling.
+func (rh *Handler) DumpOnSignal(w io.Writer, signals ...os.Signal) (stop func()) {
+	if w == nil {
+		w = os.Stderr
+	}
+	if len(signals) == 0 {
+		signals = defaultDumpSignals
+	}
+	if len(signals) == 0 {
+		return func() {} // signal.Notify() without signal would relay all incoming signals
+	}
+	c := make(chan os.Signal, 1)
+	stopped := make(chan struct{})
+	signal.Notify(c, signals...)
+	go func() {
+		for {
+			select {
+			case sig := <-c:
+				rh.dumpWithHeader(w, fmt.Sprintf("signal: %s", sig))
+			case <-stopped:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(stopped)
+		})
+	}
+}
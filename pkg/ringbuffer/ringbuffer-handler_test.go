@@ -0,0 +1,81 @@
+package ringbuffer
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func replaceDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return 'x'
+		}
+		return r
+	}, s)
+}
+
+func TestRingBufferHandler(t *testing.T) {
+	output := &bytes.Buffer{}
+	h := New(slog.NewTextHandler(output, &slog.HandlerOptions{Level: slog.LevelWarn}), &Options{Size: 3})
+	logger := slog.New(h).With(slog.String("foo", "bar"))
+	logger.Debug("debug")
+	logger.Info("info")
+	assert.Equal(t, 0, output.Len())
+	assert.Equal(t, 2, len(h.Records()))
+	logger.WithGroup("group").Warn("warn", slog.Int("key", 1))
+	logger.Info("info again")
+	assert.Contains(t, output.String(), "msg=warn foo=bar group.key=1")
+	records := h.Records()
+	assert.Equal(t, 3, len(records))
+	assert.Equal(t, "info", records[0].Message)
+	assert.Equal(t, "warn", records[1].Message)
+	assert.Equal(t, "info again", records[2].Message)
+	dump := &bytes.Buffer{}
+	assert.NoError(t, h.Dump(dump))
+	assert.Equal(t, strings.Join([]string{
+		"xxxx-xx-xxTxx:xx:xxZ [INFO ] info pkg/ringbuffer/ringbuffer-handler_test.go:xx (TestRingBufferHandler) {foo=bar}",
+		"xxxx-xx-xxTxx:xx:xxZ [WARN ] warn pkg/ringbuffer/ringbuffer-handler_test.go:xx (TestRingBufferHandler) {foo=bar group.key=x}",
+		"xxxx-xx-xxTxx:xx:xxZ [INFO ] info again pkg/ringbuffer/ringbuffer-handler_test.go:xx (TestRingBufferHandler) {foo=bar}",
+		"",
+	}, "\n"), replaceDigits(dump.String()))
+}
+
+func TestRingBufferHandlerLevel(t *testing.T) {
+	h := New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}), &Options{Level: slog.LevelInfo})
+	logger := slog.New(h)
+	logger.Debug("debug")
+	logger.Info("info")
+	assert.Equal(t, 1, len(h.Records()))
+}
+
+func TestRingBufferHandlerHTTP(t *testing.T) {
+	h := New(slog.NewTextHandler(io.Discard, nil), &Options{})
+	logger := slog.New(h)
+	logger.Debug("debug", slog.String("foo", "bar"))
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest("GET", "/?format=json", nil))
+	assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), `"level":"DEBUG","source":{`)
+	assert.Contains(t, recorder.Body.String(), `"msg":"debug","foo":"bar"}`)
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	assert.Contains(t, recorder.Body.String(), "[DEBUG] debug")
+}
+
+func TestRingBufferHandlerDumpOnPanic(t *testing.T) {
+	h := New(slog.NewTextHandler(io.Discard, nil), &Options{})
+	slog.New(h).Info("before panic")
+	dump := &bytes.Buffer{}
+	assert.PanicsWithValue(t, "boom", func() {
+		defer h.DumpOnPanic(dump)
+		panic("boom")
+	})
+	assert.Contains(t, dump.String(), "=== dump of the last log records (panic: boom) ===\n")
+	assert.Contains(t, dump.String(), "before panic")
+}
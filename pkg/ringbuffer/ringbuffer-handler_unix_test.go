@@ -0,0 +1,56 @@
+//go:build unix
+
+package ringbuffer
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRingBufferHandlerDumpOnSignal(t *testing.T) {
+	h := New(slog.NewTextHandler(io.Discard, nil), &Options{})
+	slog.New(h).Info("before signal")
+	reader, writer := io.Pipe()
+	stop := h.DumpOnSignal(writer, syscall.SIGUSR1)
+	defer stop()
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	done := make(chan string)
+	go func() {
+		buf := make([]byte, 1024)
+		n, _ := reader.Read(buf)
+		done <- string(buf[:n])
+	}()
+	select {
+	case header := <-done:
+		assert.Equal(t, "=== dump of the last log records (signal: user defined signal 1) ===\n", header)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "no dump")
+	}
+}
+
+func TestRingBufferHandlerDumpOnSignalDefault(t *testing.T) {
+	h := New(slog.NewTextHandler(io.Discard, nil), &Options{})
+	slog.New(h).Info("before signal")
+	reader, writer := io.Pipe()
+	stop := h.DumpOnSignal(writer)
+	defer stop()
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	done := make(chan string)
+	go func() {
+		buf := make([]byte, 1024)
+		n, _ := reader.Read(buf)
+		done <- string(buf[:n])
+	}()
+	select {
+	case header := <-done:
+		assert.Equal(t, "=== dump of the last log records (signal: user defined signal 1) ===\n", header)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "no dump")
+	}
+}
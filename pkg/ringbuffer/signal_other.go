@@ -0,0 +1,8 @@
+//go:build !unix
+
+package ringbuffer
+
+import "os"
+
+// defaultDumpSignals are the signals used by DumpOnSignal when no signal is given (none: there is no SIGUSR1).
+var defaultDumpSignals = []os.Signal{}
@@ -0,0 +1,11 @@
+//go:build unix
+
+package ringbuffer
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultDumpSignals are the signals used by DumpOnSignal when no signal is given.
+var defaultDumpSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,33 @@
+// sampling.Handler is a slog handler that limits the number of log records per level and per key.
+//
+// It does not output the log record itself, it decorates another slog.Handler given in the New() method.
+//
+// Each Rule applies to a level and supports two modes:
+//   - token bucket (Rate > 0, First == 0): at most Rate records per Interval (with bursts of Rate records)
+//   - "first N then every Mth" (First > 0): the First records per Interval are kept, then one record out of Thereafter
+//
+// Records are keyed by level and message (or by level and the value of the KeyAttr attribute if set, looked up
+// in the record attributes then in the attributes added with WithAttrs), so a noisy message does not prevent
+// other messages from being logged. At most MaxKeys keys are tracked at the same time: idle keys are forgotten
+// and, if all keys are busy, the records of new keys share the OverflowKey key.
+// Records with a level without rule are never sampled.
+//
+// Periodically (every SummaryInterval), a summary record is emitted for each key with suppressed records. The background
+// goroutine which emits them only runs while records are suppressed (it is started with the first suppressed record
+// and returns when all the keys are idle or when Close is called).
+//
+// Full example:
+//
+//	handler := slog.NewJSONHandler(os.Stderr, nil)
+//	samplingHandler := New(handler, &Options{
+//		Rules: []Rule{
+//			{Level: slog.LevelInfo, Rate: 100},
+//			{Level: slog.LevelDebug, First: 10, Thereafter: 100},
+//		},
+//	})
+//	defer samplingHandler.Close(context.Background())
+//	logger := slog.New(samplingHandler)
+//	for {
+//		logger.Info("at most 100 records per second")
+//	}
+package sampling
@@ -0,0 +1,104 @@
+package sampling
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IntervalDefault is the default interval of a Rule.
+const IntervalDefault = time.Second
+
+// Rule defines the sampling of records for a given level.
+type Rule struct {
+	Level      slog.Level    // The level of the records the rule applies to.
+	Rate       int           // Token bucket mode: at most Rate records per Interval (and per key).
+	First      int           // "First N then every Mth" mode: the First records per Interval (and per key) are kept...
+	Thereafter int           // ...then one record out of Thereafter (0 means that other records are dropped).
+	Interval   time.Duration // The interval of the rule (default to IntervalDefault).
+}
+
+func (r Rule) interval() time.Duration {
+	if r.Interval <= 0 {
+		return IntervalDefault
+	}
+	return r.Interval
+}
+
+// ParseRules parses a comma separated list of rules.
+//
+// Each rule is "level:rate/interval" (token bucket mode) or "level:first:thereafter/interval" ("first N then every Mth" mode).
+// The interval is a duration ("10s", "1m"...) or a single unit ("s", "m", "h").
+//
+// Example: "info:100/s,debug:10:100/s"
+func ParseRules(s string) ([]Rule, error) {
+	rules := []Rule{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		rule, err := parseRule(part)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseRule(s string) (Rule, error) {
+	var rule Rule
+	spec, interval, found := strings.Cut(s, "/")
+	if !found {
+		return rule, fmt.Errorf("invalid sampling rule: %s (missing /interval)", s)
+	}
+	d, err := parseInterval(interval)
+	if err != nil {
+		return rule, fmt.Errorf("invalid sampling rule: %s (%w)", s, err)
+	}
+	rule.Interval = d
+	fields := strings.Split(spec, ":")
+	if len(fields) != 2 && len(fields) != 3 {
+		return rule, fmt.Errorf("invalid sampling rule: %s", s)
+	}
+	err = rule.Level.UnmarshalText([]byte(fields[0]))
+	if err != nil {
+		return rule, fmt.Errorf("invalid sampling rule: %s (%w)", s, err)
+	}
+	numbers := make([]int, len(fields)-1)
+	for i, field := range fields[1:] {
+		numbers[i], err = strconv.Atoi(field)
+		if err != nil || numbers[i] < 0 {
+			return rule, fmt.Errorf("invalid sampling rule: %s (bad number: %s)", s, field)
+		}
+	}
+	if len(numbers) == 1 {
+		rule.Rate = numbers[0]
+	} else {
+		rule.First = numbers[0]
+		rule.Thereafter = numbers[1]
+	}
+	return rule, nil
+}
+
+func parseInterval(s string) (time.Duration, error) {
+	switch s {
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("interval must be positive: %s", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,29 @@
+package sampling
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("info:100/s, debug:10:100/10s,WARN:0/m")
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Level: slog.LevelInfo, Rate: 100, Interval: time.Second},
+		{Level: slog.LevelDebug, First: 10, Thereafter: 100, Interval: 10 * time.Second},
+		{Level: slog.LevelWarn, Rate: 0, Interval: time.Minute},
+	}, rules)
+	rules, err = ParseRules("")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(rules))
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	for _, s := range []string{"info:100", "foo:100/s", "info:-1/s", "info:1:2:3/s", "info:100/foo", "info:100/-1s"} {
+		_, err := ParseRules(s)
+		assert.Error(t, err, s)
+	}
+}
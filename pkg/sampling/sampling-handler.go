@@ -0,0 +1,351 @@
+package sampling
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SummaryIntervalDefault is the default interval between two summaries.
+const SummaryIntervalDefault = 10 * time.Second
+
+// SummaryMessageDefault is the default message of summary records.
+const SummaryMessageDefault = "log records suppressed by sampling"
+
+// SummaryGroupName is the name of the group of attributes added to summary records.
+const SummaryGroupName = "sampling"
+
+// MaxKeysDefault is the default maximum number of keys tracked at the same time.
+const MaxKeysDefault = 10000
+
+// OverflowKey is the key shared by the records of new keys when MaxKeys keys are already tracked.
+const OverflowKey = "(overflow)"
+
+// now is the clock used by the handler (overridden in tests).
+var now = time.Now
+
+var _ slog.Handler = &Handler{}
+
+// Options is a struct that contains the options for the (sampling) Handler.
+type Options struct {
+	Rules           []Rule        // The sampling rules (at most one per level, the last one wins).
+	KeyAttr         string        // If not empty, records are keyed by level and the value of this attribute (instead of level and message).
+	SummaryInterval time.Duration // The interval between two summaries (default to SummaryIntervalDefault, negative to disable).
+	SummaryLevel    slog.Leveler  // The level of summary records (default to slog.LevelWarn).
+	SummaryMessage  string        // The message of summary records (default to SummaryMessageDefault).
+	MaxKeys         int           // The maximum number of keys tracked at the same time (default to MaxKeysDefault), see OverflowKey.
+}
+
+type counter struct {
+	level       slog.Level
+	key         string
+	tokens      float64
+	last        time.Time
+	windowStart time.Time
+	lastSeen    time.Time
+	count       int
+	suppressed  uint64
+	seen        bool
+}
+
+// sampler is shared between a Handler and all the handlers derived from it (WithAttrs/WithGroup).
+type sampler struct {
+	opts      *Options
+	rules     map[slog.Level]Rule
+	root      slog.Handler
+	mutex     sync.Mutex
+	counters  map[string]*counter
+	lastPrune time.Time
+	running   bool          // true if the background goroutine is running
+	closed    bool          // true if Close has been called (the background goroutine is not started anymore)
+	stop      chan struct{} // closed by Close
+	done      chan struct{} // closed when the last started background goroutine returns
+	closeOnce sync.Once
+}
+
+// Handler is a slog handler that limits the number of log records per level and per key.
+type Handler struct {
+	slog.Handler
+	sampler *sampler
+	key     string // key from the attributes added with WithAttrs (if KeyAttr is set)
+}
+
+// New creates a new Handler.
+//
+// The background goroutine for periodic summaries is started with the first suppressed record and returns when
+// all the keys are idle (or when Close is called).
+//
+// Note: you should call Close before exiting the program to get the last summary.
+func New(originalHandler slog.Handler, options *Options) *Handler {
+	if options.SummaryInterval == 0 {
+		options.SummaryInterval = SummaryIntervalDefault
+	}
+	if options.SummaryLevel == nil {
+		options.SummaryLevel = slog.LevelWarn
+	}
+	if options.SummaryMessage == "" {
+		options.SummaryMessage = SummaryMessageDefault
+	}
+	if options.MaxKeys <= 0 {
+		options.MaxKeys = MaxKeysDefault
+	}
+	s := &sampler{
+		opts:     options,
+		rules:    map[slog.Level]Rule{},
+		root:     originalHandler,
+		counters: map[string]*counter{},
+		stop:     make(chan struct{}),
+	}
+	for _, rule := range options.Rules {
+		s.rules[rule.Level] = rule
+	}
+	return &Handler{
+		Handler: originalHandler,
+		sampler: s,
+	}
+}
+
+func (sh *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{
+		Handler: sh.Handler.WithGroup(name),
+		sampler: sh.sampler,
+		key:     sh.key,
+	}
+}
+
+func (sh *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	key := sh.key
+	if keyAttr := sh.sampler.opts.KeyAttr; keyAttr != "" {
+		for _, attr := range attrs {
+			if attr.Key == keyAttr {
+				key = keyAttr + "=" + attr.Value.Resolve().String()
+			}
+		}
+	}
+	return &Handler{
+		Handler: sh.Handler.WithAttrs(attrs),
+		sampler: sh.sampler,
+		key:     key,
+	}
+}
+
+// Handle forwards the record to the original handler (see constructor) if it is not suppressed by sampling.
+func (sh *Handler) Handle(context context.Context, record slog.Record) error {
+	if !sh.sampler.allow(&record, sh.key) {
+		return nil
+	}
+	return sh.Handler.Handle(context, record)
+}
+
+// Flush emits summary records for keys with suppressed records (without waiting for the next summary interval).
+func (sh *Handler) Flush(ctx context.Context) error {
+	return sh.sampler.summarize(ctx)
+}
+
+// Close stops the background goroutine (if running) and emits the last summary records.
+func (sh *Handler) Close(ctx context.Context) error {
+	s := sh.sampler
+	s.mutex.Lock()
+	s.closed = true
+	done := s.done
+	s.mutex.Unlock()
+	s.closeOnce.Do(func() {
+		close(s.stop)
+	})
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return s.summarize(ctx)
+}
+
+// key returns the key of the record: the value of the KeyAttr attribute of the record, or handlerKey
+// (from the attributes added with WithAttrs), or the message.
+func (s *sampler) key(record *slog.Record, handlerKey string) string {
+	key := record.Message
+	if handlerKey != "" {
+		key = handlerKey
+	}
+	if s.opts.KeyAttr != "" {
+		record.Attrs(func(attr slog.Attr) bool {
+			if attr.Key == s.opts.KeyAttr {
+				key = s.opts.KeyAttr + "=" + attr.Value.Resolve().String()
+				return false
+			}
+			return true
+		})
+	}
+	return key
+}
+
+func (s *sampler) allow(record *slog.Record, handlerKey string) bool {
+	rule, ok := s.rules[record.Level]
+	if !ok {
+		return true
+	}
+	key := s.key(record, handlerKey)
+	mapKey := record.Level.String() + "\x00" + key
+	t := now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	c, ok := s.counters[mapKey]
+	if !ok {
+		if len(s.counters) >= s.opts.MaxKeys {
+			s.prune(t)
+		}
+		if len(s.counters) >= s.opts.MaxKeys {
+			key = OverflowKey
+			mapKey = record.Level.String() + "\x00" + key
+			c, ok = s.counters[mapKey]
+		}
+		if !ok {
+			c = &counter{level: record.Level, key: key}
+			s.counters[mapKey] = c
+		}
+	}
+	c.seen = true
+	c.lastSeen = t
+	var allowed bool
+	if rule.First > 0 || rule.Thereafter > 0 {
+		allowed = c.allowFirstThenEvery(rule, t)
+	} else {
+		allowed = c.allowTokenBucket(rule, t)
+	}
+	if !allowed {
+		c.suppressed++
+		s.start()
+	}
+	return allowed
+}
+
+// start starts the background goroutine for periodic summaries (if not already running and not closed).
+//
+// The mutex must be held.
+func (s *sampler) start() {
+	if s.running || s.closed || s.opts.SummaryInterval <= 0 {
+		return
+	}
+	s.running = true
+	s.done = make(chan struct{})
+	go s.run(s.done)
+}
+
+// prune forgets the keys without suppressed records which have not been seen during the interval
+// of their rule (their state would be reset anyway). It is a no-op if called again during the shortest
+// rule interval (so that a full map of active keys does not cost a scan per record).
+//
+// The mutex must be held.
+func (s *sampler) prune(t time.Time) {
+	minInterval := time.Duration(0)
+	for _, rule := range s.rules {
+		if minInterval == 0 || rule.interval() < minInterval {
+			minInterval = rule.interval()
+		}
+	}
+	if !s.lastPrune.IsZero() && t.Sub(s.lastPrune) < minInterval {
+		return
+	}
+	s.lastPrune = t
+	for mapKey, c := range s.counters {
+		if c.suppressed == 0 && t.Sub(c.lastSeen) >= s.rules[c.level].interval() {
+			delete(s.counters, mapKey)
+		}
+	}
+}
+
+func (c *counter) allowTokenBucket(rule Rule, t time.Time) bool {
+	if c.last.IsZero() {
+		c.tokens = float64(rule.Rate)
+		c.last = t
+	}
+	elapsed := t.Sub(c.last)
+	if elapsed > 0 {
+		c.tokens = min(float64(rule.Rate), c.tokens+float64(rule.Rate)*elapsed.Seconds()/rule.interval().Seconds())
+		c.last = t
+	}
+	if c.tokens >= 1 {
+		c.tokens--
+		return true
+	}
+	return false
+}
+
+func (c *counter) allowFirstThenEvery(rule Rule, t time.Time) bool {
+	if c.windowStart.IsZero() || t.Sub(c.windowStart) >= rule.interval() {
+		c.windowStart = t
+		c.count = 0
+	}
+	c.count++
+	if c.count <= rule.First {
+		return true
+	}
+	return rule.Thereafter > 0 && (c.count-rule.First)%rule.Thereafter == 0
+}
+
+func (s *sampler) summarize(ctx context.Context) error {
+	s.mutex.Lock()
+	suppressed := []counter{}
+	for mapKey, c := range s.counters {
+		if c.suppressed > 0 {
+			suppressed = append(suppressed, *c)
+			c.suppressed = 0
+		} else if !c.seen {
+			delete(s.counters, mapKey) // idle key
+		}
+		c.seen = false
+	}
+	s.mutex.Unlock()
+	sort.Slice(suppressed, func(i, j int) bool {
+		if suppressed[i].level != suppressed[j].level {
+			return suppressed[i].level < suppressed[j].level
+		}
+		return suppressed[i].key < suppressed[j].key
+	})
+	level := s.opts.SummaryLevel.Level()
+	if len(suppressed) == 0 || !s.root.Enabled(ctx, level) {
+		return nil
+	}
+	for _, c := range suppressed {
+		record := slog.NewRecord(now(), level, s.opts.SummaryMessage, 0)
+		record.AddAttrs(slog.Group(SummaryGroupName,
+			slog.String("level", c.level.String()),
+			slog.String("key", c.key),
+			slog.Uint64("suppressed", c.suppressed),
+		))
+		err := s.root.Handle(ctx, record)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sampler) run(done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(s.opts.SummaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = s.summarize(context.Background())
+			s.mutex.Lock()
+			if len(s.counters) == 0 {
+				// all the keys are idle (they are deleted by summarize), restarted by the next suppressed record
+				s.running = false
+				s.mutex.Unlock()
+				return
+			}
+			s.mutex.Unlock()
+		case <-s.stop:
+			s.mutex.Lock()
+			s.running = false
+			s.mutex.Unlock()
+			return
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package sampling
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/fabien-marty/slog-helpers/pkg/external"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (fc *fakeClock) now() time.Time {
+	return fc.t
+}
+
+func useFakeClock(t *testing.T) *fakeClock {
+	fc := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	now = fc.now
+	t.Cleanup(func() { now = time.Now })
+	return fc
+}
+
+func newCollectorHandler(messages *[]string) slog.Handler {
+	return external.New(&external.Options{
+		HandlerOptions: slog.HandlerOptions{
+			Level: slog.LevelDebug,
+		},
+		StringifiedCallback: func(time time.Time, level slog.Level, message string, attrs []external.StringifiedAttr) error {
+			for _, attr := range attrs {
+				message += " " + attr.String()
+			}
+			*messages = append(*messages, message)
+			return nil
+		},
+	})
+}
+
+func TestSamplingHandlerTokenBucket(t *testing.T) {
+	fc := useFakeClock(t)
+	messages := []string{}
+	h := New(newCollectorHandler(&messages), &Options{
+		Rules:           []Rule{{Level: slog.LevelInfo, Rate: 2}},
+		SummaryInterval: -1,
+	})
+	logger := slog.New(h)
+	for i := 0; i < 5; i++ {
+		logger.Info("foo")
+		logger.Info("bar")
+		logger.Warn("not sampled")
+	}
+	assert.Equal(t, []string{"foo", "bar", "not sampled", "foo", "bar", "not sampled", "not sampled", "not sampled", "not sampled"}, messages)
+	messages = messages[:0]
+	fc.t = fc.t.Add(500 * time.Millisecond) // one token is back
+	logger.Info("foo")
+	logger.Info("foo")
+	assert.Equal(t, []string{"foo"}, messages)
+	messages = messages[:0]
+	assert.NoError(t, h.Close(context.Background()))
+	assert.Equal(t, []string{
+		"log records suppressed by sampling sampling.level=INFO sampling.key=bar sampling.suppressed=3",
+		"log records suppressed by sampling sampling.level=INFO sampling.key=foo sampling.suppressed=4",
+	}, messages)
+}
+
+func TestSamplingHandlerFirstThenEvery(t *testing.T) {
+	fc := useFakeClock(t)
+	messages := []string{}
+	h := New(newCollectorHandler(&messages), &Options{
+		Rules:           []Rule{{Level: slog.LevelDebug, First: 2, Thereafter: 3, Interval: time.Minute}},
+		SummaryInterval: -1,
+	})
+	logger := slog.New(h)
+	for i := 0; i < 10; i++ {
+		logger.Debug("foo", slog.Int("i", i))
+	}
+	assert.Equal(t, []string{"foo i=0", "foo i=1", "foo i=4", "foo i=7"}, messages)
+	messages = messages[:0]
+	fc.t = fc.t.Add(time.Minute) // new interval
+	logger.Debug("foo", slog.Int("i", 10))
+	assert.Equal(t, []string{"foo i=10"}, messages)
+}
+
+func TestSamplingHandlerKeyAttr(t *testing.T) {
+	useFakeClock(t)
+	messages := []string{}
+	h := New(newCollectorHandler(&messages), &Options{
+		Rules:           []Rule{{Level: slog.LevelInfo, Rate: 1}},
+		KeyAttr:         "user",
+		SummaryInterval: -1,
+	})
+	logger := slog.New(h).With(slog.String("common", "value"))
+	logger.Info("foo", slog.String("user", "alice"))
+	logger.Info("bar", slog.String("user", "alice"))
+	logger.Info("bar", slog.String("user", "bob"))
+	assert.Equal(t, []string{"foo common=value user=alice", "bar common=value user=bob"}, messages)
+	messages = messages[:0]
+	assert.NoError(t, h.Flush(context.Background()))
+	assert.Equal(t, []string{"log records suppressed by sampling sampling.level=INFO sampling.key=user=alice sampling.suppressed=1"}, messages)
+}
+
+func TestSamplingHandlerKeyAttrWithAttrs(t *testing.T) {
+	useFakeClock(t)
+	messages := []string{}
+	h := New(newCollectorHandler(&messages), &Options{
+		Rules:           []Rule{{Level: slog.LevelInfo, Rate: 1}},
+		KeyAttr:         "user",
+		SummaryInterval: -1,
+	})
+	alice := slog.New(h).With(slog.String("user", "alice"))
+	bob := slog.New(h).WithGroup("g").With(slog.String("user", "bob"))
+	alice.Info("foo")
+	alice.Info("bar")
+	bob.Info("foo")
+	alice.Info("baz", slog.String("user", "carol"))
+	assert.Equal(t, []string{"foo user=alice", "foo g.user=bob", "baz user=alice user=carol"}, messages)
+	messages = messages[:0]
+	assert.NoError(t, h.Flush(context.Background()))
+	assert.Equal(t, []string{"log records suppressed by sampling sampling.level=INFO sampling.key=user=alice sampling.suppressed=1"}, messages)
+}
+
+func TestSamplingHandlerMaxKeys(t *testing.T) {
+	fc := useFakeClock(t)
+	messages := []string{}
+	h := New(newCollectorHandler(&messages), &Options{
+		Rules:           []Rule{{Level: slog.LevelInfo, Rate: 1}},
+		SummaryInterval: -1,
+		MaxKeys:         2,
+	})
+	logger := slog.New(h)
+	logger.Info("foo")
+	logger.Info("bar")
+	logger.Info("baz") // no room: shares the overflow key
+	logger.Info("qux") // suppressed (overflow key)
+	assert.Equal(t, []string{"foo", "bar", "baz"}, messages)
+	assert.Len(t, h.sampler.counters, 3)
+	fc.t = fc.t.Add(time.Second) // idle keys can be forgotten
+	logger.Info("qux")
+	assert.Len(t, h.sampler.counters, 2) // overflow key (with suppressed records) + qux
+	messages = messages[:0]
+	assert.NoError(t, h.Flush(context.Background()))
+	assert.Equal(t, []string{"log records suppressed by sampling sampling.level=INFO sampling.key=(overflow) sampling.suppressed=1"}, messages)
+}
+
+func TestSamplingHandlerPeriodicSummary(t *testing.T) {
+	useFakeClock(t)
+	summaries := make(chan string, 10)
+	h := New(external.New(&external.Options{
+		StringifiedCallback: func(time time.Time, level slog.Level, message string, attrs []external.StringifiedAttr) error {
+			if message == SummaryMessageDefault {
+				summaries <- attrs[2].String()
+			}
+			return nil
+		},
+	}), &Options{
+		Rules:           []Rule{{Level: slog.LevelInfo, Rate: 1}},
+		SummaryInterval: 10 * time.Millisecond,
+	})
+	logger := slog.New(h)
+	logger.Info("foo")
+	logger.Info("foo")
+	select {
+	case summary := <-summaries:
+		assert.Equal(t, "sampling.suppressed=1", summary)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "no summary")
+	}
+	assert.NoError(t, h.Close(context.Background()))
+}
+
+func TestSamplingHandlerSummaryGoroutine(t *testing.T) {
+	useFakeClock(t)
+	h := New(slog.NewTextHandler(io.Discard, nil), &Options{
+		Rules:           []Rule{{Level: slog.LevelInfo, Rate: 1}},
+		SummaryInterval: 10 * time.Millisecond,
+	})
+	running := func() bool {
+		h.sampler.mutex.Lock()
+		defer h.sampler.mutex.Unlock()
+		return h.sampler.running
+	}
+	logger := slog.New(h)
+	logger.Info("foo")
+	assert.False(t, running()) // nothing suppressed yet
+	logger.Info("foo")
+	assert.True(t, running())
+	assert.Eventually(t, func() bool { return !running() }, 5*time.Second, time.Millisecond) // all the keys are idle
+	logger.Info("foo")
+	logger.Info("foo")
+	assert.True(t, running()) // restarted
+	assert.NoError(t, h.Close(context.Background()))
+	assert.False(t, running())
+	logger.Info("foo")
+	logger.Info("foo")
+	assert.False(t, running()) // not restarted after Close
+}
@@ -0,0 +1,38 @@
+package slogc
+
+import (
+	"context"
+	"log/slog"
+)
+
+type loggerContextKey struct{}
+
+// IntoContext returns a copy of ctx which carries the given logger.
+//
+// See FromContext to get it back.
+func IntoContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx (see IntoContext).
+//
+// If ctx does not carry any logger, slog.Default() is returned.
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok && logger != nil {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// WithAttrsContext returns a copy of ctx which carries an enriched logger: the logger of ctx (see FromContext) with the given attributes.
+//
+// This is the same than IntoContext(ctx, FromContext(ctx).With(attrs...)).
+func WithAttrsContext(ctx context.Context, attrs ...slog.Attr) context.Context {
+	args := make([]any, len(attrs))
+	for i, attr := range attrs {
+		args[i] = attr
+	}
+	return IntoContext(ctx, FromContext(ctx).With(args...))
+}
@@ -0,0 +1,37 @@
+package slogc
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/fabien-marty/slog-helpers/internal/bufferpool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromContextDefault(t *testing.T) {
+	assert.Equal(t, slog.Default(), FromContext(context.Background()))
+}
+
+func TestIntoContext(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	logger := GetLogger(WithDestinationWriter(buffer))
+	ctx := IntoContext(context.Background(), logger)
+	assert.Equal(t, logger, FromContext(ctx))
+}
+
+func TestWithAttrsContext(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	logger := GetLogger(WithDestinationWriter(buffer)).WithGroup("group")
+	ctx := IntoContext(context.Background(), logger)
+	ctx = WithAttrsContext(ctx, slog.String("request_id", "1234"))
+	ctx2 := WithAttrsContext(ctx, slog.String("user_id", "alice"))
+	FromContext(ctx2).Info("foo", slog.Int("bar", 1))
+	FromContext(ctx).Info("foo")
+	assert.Equal(t,
+		"xxxx-xx-xxTxx:xx:xxZ [INFO ] foo {group.request_id=xxxx group.user_id=alice group.bar=x}\n"+
+			"xxxx-xx-xxTxx:xx:xxZ [INFO ] foo {group.request_id=xxxx}\n",
+		replaceDigits(buffer.String()))
+}
@@ -0,0 +1,50 @@
+//go:build go1.23
+
+package slogc
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime/debug"
+	"syscall"
+)
+
+const crashModeMonitor = "monitor"
+
+func (l *Logger) captureCrashOutput() error {
+	if os.Getenv(crashEnvVar) == crashModeMonitor {
+		// we are the crash monitor: we wait for a crash report (or EOF when the monitored process exits normally)
+		signal.Ignore(os.Interrupt, syscall.SIGTERM) // the monitored process handles them
+		l.logCrashReport(readCrashReport(os.Stdin))
+		ctx, cancel := context.WithTimeout(context.Background(), FatalShutdownTimeout)
+		defer cancel()
+		_ = l.Shutdown(ctx)
+		exit(0)
+		return nil
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = crashChildEnv(crashModeMonitor)
+	cmd.Stdin = r
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Start()
+	r.Close()
+	if err != nil {
+		return err
+	}
+	go func() {
+		_ = cmd.Wait() // to avoid a zombie process if the monitor exits first
+	}()
+	return debug.SetCrashOutput(w, debug.CrashOptions{}) // w is duplicated by SetCrashOutput
+}
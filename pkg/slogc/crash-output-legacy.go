@@ -0,0 +1,76 @@
+//go:build !go1.23
+
+package slogc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+)
+
+const crashModeSupervised = "supervised"
+
+func (l *Logger) captureCrashOutput() error {
+	if os.Getenv(crashEnvVar) == crashModeSupervised {
+		// we are the supervised process => nothing to do
+		return nil
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = crashChildEnv(crashModeSupervised)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, crashForwardedSignals...)
+	go func() {
+		for sig := range signals {
+			_ = cmd.Process.Signal(sig)
+		}
+	}()
+	report := superviseStderr(stderr, os.Stderr)
+	err = cmd.Wait()
+	signal.Stop(signals)
+	l.logCrashReport(report)
+	ctx, cancel := context.WithTimeout(context.Background(), FatalShutdownTimeout)
+	defer cancel()
+	_ = l.Shutdown(ctx)
+	code := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code = crashExitCode(exitErr.ProcessState)
+	}
+	exit(code)
+	return nil
+}
+
+// superviseStderr forwards the lines of r to w until the beginning of a crash report
+// and then returns the crash report.
+func superviseStderr(r io.Reader, w io.Writer) string {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if strings.HasPrefix(line, "panic: ") || strings.HasPrefix(line, "fatal error: ") {
+			return line + readCrashReport(reader)
+		}
+		_, _ = io.WriteString(w, line)
+		if err != nil {
+			return ""
+		}
+	}
+}
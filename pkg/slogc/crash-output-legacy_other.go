@@ -0,0 +1,19 @@
+//go:build !go1.23 && !unix
+
+package slogc
+
+import (
+	"os"
+)
+
+// crashForwardedSignals are the signals forwarded by the supervisor process to the real program.
+var crashForwardedSignals = []os.Signal{os.Interrupt}
+
+// crashExitCode returns the exit code of the supervisor process for the given state of the real program
+// (1 if it was terminated without exit code).
+func crashExitCode(state *os.ProcessState) int {
+	if code := state.ExitCode(); code >= 0 {
+		return code
+	}
+	return 1
+}
@@ -0,0 +1,30 @@
+//go:build !go1.23
+
+package slogc
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrashForwardedSignals(t *testing.T) {
+	assert.Contains(t, crashForwardedSignals, os.Interrupt)
+	for _, sig := range crashForwardedSignals {
+		assert.NotEqual(t, "urgent I/O condition", sig.String()) // SIGURG (Go runtime preemption)
+		assert.NotEqual(t, "child exited", sig.String())         // SIGCHLD
+	}
+}
+
+func TestSuperviseStderr(t *testing.T) {
+	w := &bytes.Buffer{}
+	report := superviseStderr(strings.NewReader("foo\nbar\n"+crashReport), w)
+	assert.Equal(t, "foo\nbar\n", w.String())
+	assert.Equal(t, crashReport, report)
+	w.Reset()
+	assert.Equal(t, "", superviseStderr(strings.NewReader("foo\nbar"), w))
+	assert.Equal(t, "foo\nbar", w.String())
+}
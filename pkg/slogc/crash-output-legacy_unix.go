@@ -0,0 +1,22 @@
+//go:build !go1.23 && unix
+
+package slogc
+
+import (
+	"os"
+	"syscall"
+)
+
+// crashForwardedSignals are the signals forwarded by the supervisor process to the real program.
+//
+// Note: signal.Notify() without signal would also relay SIGURG (used by the Go runtime for preemption), SIGCHLD...
+var crashForwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGUSR2}
+
+// crashExitCode returns the exit code of the supervisor process for the given state of the real program
+// (128 + the signal number if it was killed by a signal, like shells do).
+func crashExitCode(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
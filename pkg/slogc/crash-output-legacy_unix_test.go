@@ -0,0 +1,22 @@
+//go:build !go1.23 && unix
+
+package slogc
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrashExitCode(t *testing.T) {
+	var exitErr *exec.ExitError
+	err := exec.Command("sh", "-c", "exit 3").Run()
+	assert.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, 3, crashExitCode(exitErr.ProcessState))
+	err = exec.Command("sh", "-c", "kill -TERM $$").Run()
+	assert.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, 128+int(syscall.SIGTERM), crashExitCode(exitErr.ProcessState))
+}
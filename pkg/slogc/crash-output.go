@@ -0,0 +1,74 @@
+package slogc
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/fabien-marty/slog-helpers/pkg/stacktrace"
+)
+
+// crashEnvVar is the environment variable which marks the processes started by CaptureCrashOutput.
+const crashEnvVar = "SLOGC_CRASH_OUTPUT"
+
+// CrashMessageDefault is the message of the crash records when the crash report has no header line.
+const CrashMessageDefault = "go runtime crash"
+
+// KeyCrashReport is the key of the attribute with the full crash report (if it is not only a stack trace).
+const KeyCrashReport = "crash_report"
+
+// CaptureCrashOutput routes the crash reports of the Go runtime (unrecovered panics, fatal errors like
+// concurrent map writes, out of memory, deadlocks...) to the logger as a single FATAL record (in the
+// configured format and destination) instead of raw multi-line dumps on stderr.
+//
+// It works by re-executing the program (with the same arguments and an additional environment variable):
+//   - with Go >= 1.23, the child process is a crash monitor which receives the crash report (see debug.SetCrashOutput)
+//   - with older versions, the child process is the real program and the current process supervises its stderr
+//     (and then exits with the exit code of the child)
+//
+// So it must be called at the very beginning of the main function (just after creating the logger with
+// the same options in both processes).
+//
+// Note: with Go >= 1.23, the Go runtime still writes the raw crash report on stderr (so use stdout as
+// log destination if you want a stream with structured records only).
+func (l *Logger) CaptureCrashOutput() error {
+	return l.captureCrashOutput()
+}
+
+// logCrashReport logs the given crash report as a FATAL record (nothing is logged for an empty report).
+func (l *Logger) logCrashReport(report string) {
+	report = strings.TrimSpace(report)
+	if report == "" {
+		return
+	}
+	message, stack, _ := strings.Cut(report, "\n")
+	attrs := []slog.Attr{}
+	// the header can be multi-line (for example "panic: ... [recovered]" lines or "fatal error: ..." + "runtime: ..." lines)
+	header, goroutines, found := strings.Cut(stack, "\ngoroutine ")
+	if found {
+		if strings.TrimSpace(header) != "" {
+			attrs = append(attrs, slog.String(KeyCrashReport, report))
+		}
+		stack = "goroutine " + goroutines
+	}
+	if message == "" || strings.HasPrefix(message, "goroutine ") {
+		message = CrashMessageDefault
+	}
+	attrs = append(attrs,
+		slog.String(KeyStackTrace, strings.TrimSpace(stack)),
+		slog.Bool(stacktrace.KeyForStackTraceEnabledDefault, false), // we already have the real stack trace
+	)
+	l.LogAttrs(context.Background(), LevelFatal, message, attrs...)
+}
+
+// readCrashReport reads a crash report until EOF (or until max bytes).
+func readCrashReport(r io.Reader) string {
+	b, _ := io.ReadAll(io.LimitReader(r, 10*1024*1024))
+	return string(b)
+}
+
+func crashChildEnv(mode string) []string {
+	return append(os.Environ(), crashEnvVar+"="+mode)
+}
@@ -0,0 +1,78 @@
+package slogc
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const crashReport = `fatal error: concurrent map writes
+
+goroutine 7 [running]:
+main.main.func1()
+	/tmp/main.go:12 +0x3c
+created by main.main in goroutine 1
+	/tmp/main.go:10 +0x5c
+`
+
+func TestLogCrashReport(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatJson))
+	assert.NoError(t, err)
+	l.logCrashReport("")
+	assert.Equal(t, "", buffer.String())
+	l.logCrashReport(crashReport)
+	records := decodeLines(t, buffer)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "FATAL", records[0]["level"])
+	assert.Equal(t, "fatal error: concurrent map writes", records[0]["msg"])
+	assert.True(t, strings.HasPrefix(records[0][KeyStackTrace].(string), "goroutine 7 [running]:\nmain.main.func1()"))
+	assert.NotContains(t, records[0], KeyCrashReport)
+	assert.NotContains(t, records[0], "add-stacktrace") // stack traces are disabled
+	buffer.Reset()
+	l.logCrashReport("panic: boom [recovered]\n\tpanic: boom again\n\ngoroutine 1 [running]:\nmain.main()\n")
+	records = decodeLines(t, buffer)
+	assert.Equal(t, "panic: boom [recovered]", records[0]["msg"])
+	assert.Equal(t, "goroutine 1 [running]:\nmain.main()", records[0][KeyStackTrace])
+	assert.Contains(t, records[0][KeyCrashReport], "panic: boom again")
+}
+
+func TestCaptureCrashOutputHelper(t *testing.T) {
+	if os.Getenv("SLOGC_CRASH_HELPER") != "1" {
+		t.Skip("helper process for TestCaptureCrashOutput")
+	}
+	l, err := New(WithDestination(LogDestinationStdout), WithLogFormat(LogFormatJson))
+	if err != nil {
+		os.Exit(3)
+	}
+	err = l.CaptureCrashOutput()
+	if err != nil {
+		os.Exit(4)
+	}
+	var m map[string]int
+	m["crash"] = 1 // unrecovered panic
+}
+
+func TestCaptureCrashOutput(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCaptureCrashOutputHelper$")
+	cmd.Env = append(os.Environ(), "SLOGC_CRASH_HELPER=1")
+	stdout := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	err := cmd.Run()
+	assert.Error(t, err) // the helper process crashed
+	jsonLines := &bytes.Buffer{}
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if strings.HasPrefix(line, "{") { // we ignore the output of the test framework
+			jsonLines.WriteString(line + "\n")
+		}
+	}
+	records := decodeLines(t, jsonLines)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "FATAL", records[0]["level"])
+	assert.True(t, strings.HasPrefix(records[0]["msg"].(string), "panic: assignment to entry in nil map"))
+	assert.Contains(t, records[0][KeyStackTrace], "TestCaptureCrashOutputHelper")
+}
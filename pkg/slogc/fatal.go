@@ -0,0 +1,89 @@
+package slogc
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/fabien-marty/slog-helpers/pkg/stacktrace"
+)
+
+// FatalShutdownTimeout is the maximum duration of the logger shutdown in Fatal (before exiting).
+const FatalShutdownTimeout = 5 * time.Second
+
+// Fatal logs a record at LevelFatal (with a stack trace), then shuts down the logger (to flush buffered
+// and async outputs, see Shutdown) within FatalShutdownTimeout and exits the program with code 1.
+//
+// If logger is nil, slog.Default() is used. The exit function can be overridden with SetExitFunc.
+func Fatal(logger *slog.Logger, msg string, attrs ...slog.Attr) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	attrs = append(attrs, slog.Bool(stacktrace.KeyForStackTraceEnabledDefault, true))
+	logger.LogAttrs(context.Background(), LevelFatal, msg, attrs...)
+	shutdownAndExit(logger, 1)
+}
+
+// shutdownAndExit shuts down the logger (within FatalShutdownTimeout) and exits the program with the given code.
+func shutdownAndExit(logger *slog.Logger, code int) {
+	ctx, cancel := context.WithTimeout(context.Background(), FatalShutdownTimeout)
+	defer cancel()
+	_ = Shutdown(ctx, logger)
+	exit(code)
+}
+
+// fatalStackTraceHandler is a handler which only sends the records with a level >= LevelFatal to a stacktrace.Handler
+// (used when stack traces are disabled, other records are forwarded to the original handler without their
+// add-stacktrace attribute).
+type fatalStackTraceHandler struct {
+	slog.Handler              // the original handler
+	fatal        slog.Handler // the original handler decorated with a stacktrace.Handler
+}
+
+func newFatalStackTraceHandler(originalHandler slog.Handler, options *stacktrace.Options) *fatalStackTraceHandler {
+	level := LevelFatal
+	options.MinimalLevelForStackTraceEnabledEnabled = &level
+	return &fatalStackTraceHandler{
+		Handler: originalHandler,
+		fatal:   stacktrace.New(originalHandler, options),
+	}
+}
+
+func (fh *fatalStackTraceHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= LevelFatal {
+		return fh.fatal.Handle(ctx, record)
+	}
+	return fh.Handler.Handle(ctx, withoutStackTraceKey(record))
+}
+
+// withoutStackTraceKey returns the record without its stacktrace.KeyForStackTraceEnabledDefault attributes
+// (the record itself if it has none).
+func withoutStackTraceKey(record slog.Record) slog.Record {
+	found := false
+	record.Attrs(func(attr slog.Attr) bool {
+		found = attr.Key == stacktrace.KeyForStackTraceEnabledDefault
+		return !found
+	})
+	if !found {
+		return record
+	}
+	newRecord := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key != stacktrace.KeyForStackTraceEnabledDefault {
+			newRecord.AddAttrs(attr)
+		}
+		return true
+	})
+	return newRecord
+}
+
+func (fh *fatalStackTraceHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return fh
+	}
+	return &fatalStackTraceHandler{Handler: fh.Handler.WithGroup(name), fatal: fh.fatal.WithGroup(name)}
+}
+
+func (fh *fatalStackTraceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &fatalStackTraceHandler{Handler: fh.Handler.WithAttrs(attrs), fatal: fh.fatal.WithAttrs(attrs)}
+}
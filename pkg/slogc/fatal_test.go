@@ -0,0 +1,122 @@
+package slogc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/fabien-marty/slog-helpers/pkg/external"
+	"github.com/fabien-marty/slog-helpers/pkg/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFatal(t *testing.T) {
+	code := 0
+	SetExitFunc(func(c int) { code = c })
+	defer SetExitFunc(nil)
+	buffer := &bytes.Buffer{}
+	writer := bufio.NewWriter(buffer)
+	logger := GetLogger(WithDestinationWriter(writer), WithLogFormat(LogFormatJson), WithAsync(16, "")).With(slog.String("foo", "bar"))
+	Fatal(logger, "fatal error", slog.Int("answer", 42))
+	assert.Equal(t, 1, code)
+	records := decodeLines(t, buffer) // flushed (async and bufio) before exit
+	assert.Len(t, records, 1)
+	assert.Equal(t, "FATAL", records[0]["level"])
+	assert.Equal(t, "fatal error", records[0]["msg"])
+	assert.Equal(t, "bar", records[0]["foo"])
+	assert.Equal(t, float64(42), records[0]["answer"])
+	assert.Contains(t, records[0]["stacktrace"], "TestFatal")
+}
+
+func TestFatalLevel(t *testing.T) {
+	assert.Equal(t, slog.LevelError, GetLogLevelFromString("fatal")) // backward compatibility
+	buffer := &bytes.Buffer{}
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatJsonGcp), WithLevel(LevelFatal), WithStackTraceLevel(LevelFatal))
+	assert.NoError(t, err)
+	l.Error("filtered")
+	l.Log(context.Background(), LevelFatal, "fatal")
+	records := decodeLines(t, buffer)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "CRITICAL", records[0]["severity"])
+}
+
+func TestFatalStackTraceOnly(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatJson), WithStackTrace(false))
+	assert.NoError(t, err)
+	l.Error("error", slog.Bool(stacktrace.KeyForStackTraceEnabledDefault, true))
+	l.With(slog.String("foo", "bar")).Log(context.Background(), LevelFatal, "fatal", slog.Bool(stacktrace.KeyForStackTraceEnabledDefault, true))
+	records := decodeLines(t, buffer)
+	assert.Len(t, records, 2)
+	assert.NotContains(t, records[0], "stacktrace")
+	assert.NotContains(t, records[0], stacktrace.KeyForStackTraceEnabledDefault)
+	assert.Contains(t, records[1]["stacktrace"], "TestFatalStackTraceOnly")
+	assert.Equal(t, "bar", records[1]["foo"])
+	assert.NotContains(t, records[1], stacktrace.KeyForStackTraceEnabledDefault)
+}
+
+func TestFatalReplaceAttr(t *testing.T) {
+	var levels []slog.Level
+	replace := func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.LevelKey {
+			level := a.Value.Any().(slog.Level) // the user hook always gets the original level
+			levels = append(levels, level)
+			if level >= slog.LevelError {
+				return slog.Attr{Key: a.Key, Value: slog.AnyValue(level)} // kept as a slog.Level
+			}
+			return slog.String("lvl", "low")
+		}
+		if a.Key == "password" {
+			return slog.String(a.Key, "REDACTED")
+		}
+		return a
+	}
+	for format, keys := range map[LogFormat][2]string{
+		LogFormatJson:    {"level", "FATAL"},
+		LogFormatText:    {"level", "FATAL"},
+		LogFormatJsonGcp: {"severity", "CRITICAL"},
+	} {
+		levels = nil
+		buffer := &bytes.Buffer{}
+		l, err := New(WithDestinationWriter(buffer), WithLogFormat(format), WithStackTrace(false), WithReplaceAttr(replace))
+		assert.NoError(t, err)
+		l.Log(context.Background(), LevelFatal, "fatal", slog.String("password", "secret"))
+		l.Info("info")
+		assert.Equal(t, []slog.Level{LevelFatal, slog.LevelInfo}, levels, format)
+		if format == LogFormatText {
+			assert.Contains(t, buffer.String(), " level=FATAL ")
+			assert.Contains(t, buffer.String(), " password=REDACTED")
+			assert.Contains(t, buffer.String(), " lvl=low ")
+			continue
+		}
+		records := decodeLines(t, buffer)
+		assert.Len(t, records, 2)
+		assert.Equal(t, "REDACTED", records[0]["password"], format)
+		assert.Equal(t, keys[1], records[0][keys[0]], format)
+		assert.Equal(t, "low", records[1]["lvl"], format)
+	}
+}
+
+func TestFatalExternal(t *testing.T) {
+	var fatalAttrs, errorAttrs []string
+	l, err := New(WithStackTrace(false), WithExternalStringifiedAttrsCallback(func(_ time.Time, level slog.Level, _ string, attrs []external.StringifiedAttr) error {
+		keys := []string{}
+		for _, attr := range attrs {
+			keys = append(keys, attr.Key)
+		}
+		if level == LevelFatal {
+			fatalAttrs = keys
+		} else {
+			errorAttrs = keys
+		}
+		return nil
+	}))
+	assert.NoError(t, err)
+	l.Error("error", slog.Bool(stacktrace.KeyForStackTraceEnabledDefault, false))
+	l.Log(context.Background(), LevelFatal, "fatal", slog.Bool(stacktrace.KeyForStackTraceEnabledDefault, true))
+	assert.Equal(t, []string{}, errorAttrs)
+	assert.Equal(t, []string{stacktrace.KeyNameForModeAddAttrDefault}, fatalAttrs)
+}
@@ -0,0 +1,247 @@
+package slogc
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fabien-marty/slog-helpers/pkg/combined"
+	"github.com/fabien-marty/slog-helpers/pkg/stacktrace"
+	"github.com/fabien-marty/slog-helpers/pkg/tracecontext"
+)
+
+// RequestIDHeaderDefault is the default header used to propagate the request ID.
+const RequestIDHeaderDefault = "X-Request-Id"
+
+// RequestIDMaxLength is the maximal length of a request ID received in the request header (longer ones are replaced).
+const RequestIDMaxLength = 128
+
+// HTTPMessageDefault is the default message of the access records.
+const HTTPMessageDefault = "http request"
+
+// HTTPPanicMessageDefault is the default message of the records logged when a handler panics.
+const HTTPPanicMessageDefault = "panic in http handler"
+
+// Keys of the attributes added by HTTPMiddleware (the well-known HTTP attributes are rendered by LogFormatCombined).
+const (
+	KeyRequestID           = "request_id"
+	KeyHTTPGroup           = combined.KeyGroup
+	KeyHTTPMethod          = combined.KeyMethod
+	KeyHTTPPath            = combined.KeyPath
+	KeyHTTPQuery           = combined.KeyQuery
+	KeyHTTPProto           = combined.KeyProto
+	KeyHTTPStatus          = combined.KeyStatus
+	KeyHTTPBytes           = combined.KeyBytes
+	KeyHTTPDuration        = "duration"
+	KeyHTTPRemoteAddr      = combined.KeyRemoteAddr
+	KeyHTTPUserAgent       = combined.KeyUserAgent
+	KeyHTTPReferer         = combined.KeyReferer
+	KeyHTTPRequestHeaders  = "request_headers"
+	KeyHTTPResponseHeaders = "response_headers"
+	KeyPanic               = "panic"
+)
+
+// HTTPMiddlewareOptions is a struct that contains the options for HTTPMiddleware.
+type HTTPMiddlewareOptions struct {
+	RequestIDHeader        string   // The header used to get/propagate the request ID (default to RequestIDHeaderDefault).
+	Message                string   // The message of the access records (default to HTTPMessageDefault).
+	SkipPaths              []string // Exact paths for which no access record is logged (health checks for example).
+	CaptureRequestHeaders  []string // Request headers to add to the access records.
+	CaptureResponseHeaders []string // Response headers to add to the access records.
+}
+
+// responseWriter is a http.ResponseWriter which records the status code and the number of written bytes.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Hijack makes websockets (and other protocol upgrades) work through the middleware.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijack not supported by %T: %w", rw.ResponseWriter, http.ErrNotSupported)
+	}
+	conn, brw, err := h.Hijack()
+	if err == nil && rw.status == 0 {
+		rw.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+// Unwrap is used by http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// isValidRequestID returns true if the request ID (received in the request header) can be trusted:
+// not empty, not too long (see RequestIDMaxLength) and only made of ASCII letters, digits and "-_.:+/=".
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > RequestIDMaxLength {
+		return false
+	}
+	for _, c := range []byte(requestID) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.IndexByte("-_.:+/=", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// HTTPMiddleware returns a net/http middleware which:
+//   - gets the request ID from the request header (or generates a new one if missing or invalid: longer than
+//     RequestIDMaxLength or with other characters than ASCII letters, digits and "-_.:+/=") and sets it in the response header
+//   - stores the trace context of the "traceparent" header in the request context (see tracecontext package)
+//   - stores an enriched logger (with a request_id attribute) in the request context (see FromContext)
+//   - logs one access record when the request is completed (with a level derived from the status code: Error for 5xx, Warn for 4xx, Info for others)
+//   - recovers panics of the next handler, logs them (with the panic value, its type and the stack of the handler) and
+//     returns a 500 error if nothing has been sent yet (the access record gets a panic=true attribute and the Error level)
+//
+// If logger is nil, slog.Default() is used.
+func HTTPMiddleware(logger *slog.Logger, opts *HTTPMiddlewareOptions) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if opts == nil {
+		opts = &HTTPMiddlewareOptions{}
+	}
+	if opts.RequestIDHeader == "" {
+		opts.RequestIDHeader = RequestIDHeaderDefault
+	}
+	if opts.Message == "" {
+		opts.Message = HTTPMessageDefault
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			requestID := r.Header.Get(opts.RequestIDHeader)
+			if !isValidRequestID(requestID) {
+				requestID = newRequestID()
+			}
+			w.Header().Set(opts.RequestIDHeader, requestID)
+			ctx := r.Context()
+			if tc, err := tracecontext.ParseTraceparent(r.Header.Get("traceparent")); err == nil {
+				ctx = tracecontext.NewContext(ctx, tc)
+			}
+			requestLogger := logger.With(slog.String(KeyRequestID, requestID))
+			ctx = IntoContext(ctx, requestLogger)
+			r = r.WithContext(ctx)
+			rw := &responseWriter{ResponseWriter: w}
+			defer func() {
+				panicked := false
+				if v := recover(); v != nil {
+					if v == http.ErrAbortHandler {
+						panic(v)
+					}
+					panicked = true
+					requestLogger.ErrorContext(ctx, HTTPPanicMessageDefault,
+						slog.String(KeyPanic, fmt.Sprint(v)),
+						slog.String(KeyPanicType, fmt.Sprintf("%T", v)),
+						slog.String(KeyStackTrace, panickingStack(debug.Stack())),
+						slog.Bool(stacktrace.KeyForStackTraceEnabledDefault, false), // we already have a better stack trace
+					)
+					if rw.status == 0 {
+						// nothing has been sent yet
+						http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					}
+				}
+				if slices.Contains(opts.SkipPaths, r.URL.Path) {
+					return
+				}
+				if rw.status == 0 {
+					rw.status = http.StatusOK
+				}
+				level := slog.LevelInfo
+				if rw.status >= 500 || panicked {
+					level = slog.LevelError
+				} else if rw.status >= 400 {
+					level = slog.LevelWarn
+				}
+				attrs := []any{
+					slog.String(KeyHTTPMethod, r.Method),
+					slog.String(KeyHTTPPath, r.URL.Path),
+				}
+				if r.URL.RawQuery != "" {
+					attrs = append(attrs, slog.String(KeyHTTPQuery, r.URL.RawQuery))
+				}
+				attrs = append(attrs,
+					slog.String(KeyHTTPProto, r.Proto),
+					slog.Int(KeyHTTPStatus, rw.status),
+					slog.Int(KeyHTTPBytes, rw.bytes),
+					slog.Duration(KeyHTTPDuration, time.Since(start)),
+					slog.String(KeyHTTPRemoteAddr, r.RemoteAddr),
+					slog.String(KeyHTTPUserAgent, r.UserAgent()),
+				)
+				if referer := r.Referer(); referer != "" {
+					attrs = append(attrs, slog.String(KeyHTTPReferer, referer))
+				}
+				if headers := captureHeaders(r.Header, opts.CaptureRequestHeaders); len(headers) > 0 {
+					attrs = append(attrs, slog.Group(KeyHTTPRequestHeaders, headers...))
+				}
+				if headers := captureHeaders(rw.Header(), opts.CaptureResponseHeaders); len(headers) > 0 {
+					attrs = append(attrs, slog.Group(KeyHTTPResponseHeaders, headers...))
+				}
+				if panicked {
+					// the status may have been sent before the panic
+					requestLogger.LogAttrs(ctx, level, opts.Message, slog.Group(KeyHTTPGroup, attrs...), slog.Bool(KeyPanic, true))
+					return
+				}
+				requestLogger.LogAttrs(ctx, level, opts.Message, slog.Group(KeyHTTPGroup, attrs...))
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+func captureHeaders(header http.Header, names []string) []any {
+	var attrs []any
+	for _, name := range names {
+		if values := header.Values(name); len(values) > 0 {
+			attrs = append(attrs, slog.String(strings.ToLower(name), strings.Join(values, ", ")))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,202 @@
+package slogc
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeLines(t *testing.T, buffer *bytes.Buffer) []map[string]any {
+	var res []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		var decoded map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &decoded))
+		res = append(res, decoded)
+	}
+	return res
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatJson))
+	assert.NoError(t, err)
+	middleware := HTTPMiddleware(l.Logger, &HTTPMiddlewareOptions{
+		SkipPaths:              []string{"/healthz"},
+		CaptureRequestHeaders:  []string{"X-Foo"},
+		CaptureResponseHeaders: []string{"Content-Type"},
+	})
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).InfoContext(r.Context(), "in handler")
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/foo?bar=baz", nil)
+	req.Header.Set("X-Foo", "foo")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	requestID := rec.Header().Get(RequestIDHeaderDefault)
+	assert.Len(t, requestID, 32)
+	records := decodeLines(t, buffer)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "in handler", records[0]["msg"])
+	assert.Equal(t, requestID, records[0][KeyRequestID])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", records[0]["trace_id"])
+	assert.Equal(t, HTTPMessageDefault, records[1]["msg"])
+	assert.Equal(t, "INFO", records[1]["level"])
+	assert.Equal(t, requestID, records[1][KeyRequestID])
+	h := records[1][KeyHTTPGroup].(map[string]any)
+	assert.Equal(t, "GET", h[KeyHTTPMethod])
+	assert.Equal(t, "/foo", h[KeyHTTPPath])
+	assert.Equal(t, "bar=baz", h[KeyHTTPQuery])
+	assert.Equal(t, float64(200), h[KeyHTTPStatus])
+	assert.Equal(t, float64(5), h[KeyHTTPBytes])
+	assert.Contains(t, h, KeyHTTPDuration)
+	assert.Contains(t, h, KeyHTTPRemoteAddr)
+	assert.Equal(t, map[string]any{"x-foo": "foo"}, h[KeyHTTPRequestHeaders])
+	assert.Equal(t, map[string]any{"content-type": "text/plain"}, h[KeyHTTPResponseHeaders])
+
+	buffer.Reset()
+	req = httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set(RequestIDHeaderDefault, "1234")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "1234", rec.Header().Get(RequestIDHeaderDefault))
+	records = decodeLines(t, buffer)
+	assert.Equal(t, "WARN", records[1]["level"])
+	assert.Equal(t, "1234", records[1][KeyRequestID])
+
+	for _, invalid := range []string{"12 34", "1234\nfoo=bar", "\"1234\"", strings.Repeat("x", RequestIDMaxLength+1)} {
+		req = httptest.NewRequest(http.MethodGet, "/foo", nil)
+		req.Header.Set(RequestIDHeaderDefault, invalid)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Len(t, rec.Header().Get(RequestIDHeaderDefault), 32, "invalid request id: %q", invalid)
+	}
+
+	buffer.Reset()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	records = decodeLines(t, buffer)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "in handler", records[0]["msg"])
+}
+
+func TestHTTPMiddlewareHijackAndFlush(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatJson))
+	assert.NoError(t, err)
+	done := make(chan struct{}, 2)
+	handler := HTTPMiddleware(l.Logger, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sse" {
+			_, _ = w.Write([]byte("data: foo\n\n"))
+			assert.NoError(t, http.NewResponseController(w).Flush())
+			return
+		}
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: foo\r\nConnection: Upgrade\r\n\r\n")
+		_ = brw.Flush()
+	}))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+		done <- struct{}{} // the access record is logged
+	}))
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/sse")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/ws", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "foo")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	<-done
+	<-done
+	records := decodeLines(t, buffer)
+	assert.Len(t, records, 2)
+	assert.Equal(t, float64(200), records[0][KeyHTTPGroup].(map[string]any)[KeyHTTPStatus])
+	assert.Equal(t, float64(101), records[1][KeyHTTPGroup].(map[string]any)[KeyHTTPStatus])
+}
+
+func TestHTTPMiddlewarePanic(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatJson), WithStackTrace(true))
+	assert.NoError(t, err)
+	handler := HTTPMiddleware(l.Logger, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/foo", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	records := decodeLines(t, buffer)
+	assert.Len(t, records, 2)
+	assert.Equal(t, HTTPPanicMessageDefault, records[0]["msg"])
+	assert.Equal(t, "boom", records[0][KeyPanic])
+	assert.Equal(t, "string", records[0][KeyPanicType])
+	assert.Contains(t, records[0][KeyStackTrace], "TestHTTPMiddlewarePanic")
+	assert.NotContains(t, records[0][KeyStackTrace], "runtime/debug.Stack")
+	assert.NotContains(t, records[0], "add-stacktrace")
+	assert.Equal(t, "ERROR", records[1]["level"])
+	assert.Equal(t, float64(500), records[1][KeyHTTPGroup].(map[string]any)[KeyHTTPStatus])
+	assert.Equal(t, true, records[1][KeyPanic])
+
+	abort := HTTPMiddleware(slog.Default(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		abort.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestHTTPMiddlewarePanicAfterWrite(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buffer, nil)) // no stack trace handler
+	handler := HTTPMiddleware(logger, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		panic("boom")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/foo", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	records := decodeLines(t, buffer)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "boom", records[0][KeyPanic])
+	assert.Contains(t, records[0][KeyStackTrace], "TestHTTPMiddlewarePanicAfterWrite")
+	assert.Equal(t, "ERROR", records[1]["level"])
+	assert.Equal(t, float64(200), records[1][KeyHTTPGroup].(map[string]any)[KeyHTTPStatus])
+	assert.Equal(t, true, records[1][KeyPanic])
+}
+
+func TestHTTPMiddlewareCombined(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(GetLogFormatFromString("combined")))
+	assert.NoError(t, err)
+	handler := HTTPMiddleware(l.Logger, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/foo", nil)
+	req.Header.Set(RequestIDHeaderDefault, "1234")
+	req.Header.Set("User-Agent", "curl/8.0")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	line := buffer.String()
+	assert.True(t, strings.HasPrefix(line, "192.0.2.1 - - ["), line)
+	assert.Contains(t, line, `] "GET /foo HTTP/1.1" 200 5 "-" "curl/8.0" request_id=1234 http.duration=`)
+}
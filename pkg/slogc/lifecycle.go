@@ -0,0 +1,146 @@
+package slogc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// exit is the function used to exit the program (see SetExitFunc).
+var exit = os.Exit
+
+// SetExitFunc sets the function used to exit the program by Fatal, RecoverAndLog and ShutdownOnSignals
+// (default to os.Exit, nil restores it).
+//
+// It is useful in tests. It must not be called concurrently with logging.
+func SetExitFunc(f func(code int)) {
+	if f == nil {
+		f = os.Exit
+	}
+	exit = f
+}
+
+// contextFlusher is implemented by handlers which can delay the output of records (for example async.Handler).
+type contextFlusher interface {
+	Flush(ctx context.Context) error
+}
+
+// contextCloser is implemented by handlers which hold resources (for example async.Handler).
+type contextCloser interface {
+	Close(ctx context.Context) error
+}
+
+// flusher is implemented by buffered writers (for example bufio.Writer).
+type flusher interface {
+	Flush() error
+}
+
+// lifecycle holds the components of a handler chain which must be flushed/closed at shutdown.
+type lifecycle struct {
+	mutex      sync.Mutex
+	components []any // from the outermost to the innermost
+	done       bool
+	err        error
+}
+
+// add registers a new outermost component.
+func (lc *lifecycle) add(component any) {
+	lc.components = append([]any{component}, lc.components...)
+}
+
+func (lc *lifecycle) shutdown(ctx context.Context) error {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	if lc.done {
+		return lc.err
+	}
+	var errs []error
+	for _, component := range lc.components {
+		switch c := component.(type) {
+		case contextCloser:
+			errs = append(errs, c.Close(ctx))
+		case contextFlusher:
+			errs = append(errs, c.Flush(ctx))
+		case flusher:
+			errs = append(errs, c.Flush())
+		}
+	}
+	lc.done = true
+	lc.err = errors.Join(errs...)
+	return lc.err
+}
+
+// lifecycleHandler is the outermost handler of the loggers created by New (it makes the lifecycle
+// reachable from a *slog.Logger, see Shutdown).
+type lifecycleHandler struct {
+	slog.Handler
+	lifecycle *lifecycle
+}
+
+func (lh *lifecycleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return lh
+	}
+	return &lifecycleHandler{Handler: lh.Handler.WithGroup(name), lifecycle: lh.lifecycle}
+}
+
+func (lh *lifecycleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &lifecycleHandler{Handler: lh.Handler.WithAttrs(attrs), lifecycle: lh.lifecycle}
+}
+
+// Shutdown is the same than (*Logger).Shutdown but for a *slog.Logger (returned by GetLogger for example).
+//
+// If the logger has not been created by New or GetLogger (or derived from such a logger), only its handler is
+// flushed/closed (if it supports it).
+func Shutdown(ctx context.Context, logger *slog.Logger) error {
+	switch h := logger.Handler().(type) {
+	case *lifecycleHandler:
+		return h.lifecycle.shutdown(ctx)
+	case contextCloser:
+		return h.Close(ctx)
+	case contextFlusher:
+		return h.Flush(ctx)
+	}
+	return nil
+}
+
+// ShutdownOnSignals starts a goroutine that shuts down the logger and exits the program when one of the given signals is received.
+//
+// If no signal is given, SIGINT and SIGTERM are used. The shutdown is limited to the given timeout
+// and the exit code is 128 + the signal number (130 for SIGINT, 143 for SIGTERM) like shells do.
+//
+// The returned function stops the signal handling.
+func (l *Logger) ShutdownOnSignals(timeout time.Duration, signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	c := make(chan os.Signal, 1)
+	stopped := make(chan struct{})
+	signal.Notify(c, signals...)
+	go func() {
+		select {
+		case sig := <-c:
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			_ = l.Shutdown(ctx)
+			code := 1
+			if s, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(s)
+			}
+			exit(code)
+		case <-stopped:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(stopped)
+		})
+	}
+}
@@ -25,6 +25,10 @@ const LogFormatJson LogFormat = "json"
 // LogFormatJsonGcp is the JSON format for Google Cloud Platform (GCP).
 const LogFormatJsonGcp LogFormat = "json-gcp"
 
+// LogFormatCombined is the NCSA Combined Log Format (Apache/nginx) for HTTP access records (see HTTPMiddleware),
+// other records are rendered with the basic/standard text format.
+const LogFormatCombined LogFormat = "combined"
+
 // LogFormatExternal is the external format (log records are not rendered by the logger but sent to an external handler)
 const LogFormatExternal LogFormat = "external"
 
@@ -54,6 +58,8 @@ func GetLogFormatFromString(logLevel string) LogFormat {
 		return LogFormatJson
 	case "json-gcp", "gcp":
 		return LogFormatJsonGcp
+	case "combined":
+		return LogFormatCombined
 	case "external":
 		return LogFormatExternal
 	}
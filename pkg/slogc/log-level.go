@@ -5,6 +5,8 @@ import (
 	"os"
 	"strings"
 	"sync"
+
+	"github.com/fabien-marty/slog-helpers/pkg/human"
 )
 
 // DefaultLogLevelEnvVar is the default environment variable used to define the default log level.
@@ -12,6 +14,11 @@ import (
 // The default value "LOG_LEVEL" can be overridden with SetLogLevelEnvVar.
 const DefaultLogLevelEnvVar = "LOG_LEVEL"
 
+// LevelFatal is the level of the records logged by Fatal (rendered as "FATAL", "CRITICAL" severity for GCP).
+//
+// It is the same level than human.LevelFatal.
+const LevelFatal = human.LevelFatal
+
 // DefaultLogLevel is the default log level.
 const DefaultLogLevel = slog.LevelInfo
 
@@ -28,6 +35,9 @@ func SetLogLevelEnvVar(envVar string) {
 // GetLogLevelFromString returns the log level from a string.
 //
 // The log level is case insensitive. If the string is not recognized, the default log level is returned.
+//
+// Note: "FATAL" is parsed as slog.LevelError (and not as LevelFatal) for backward compatibility. Use
+// WithLevel(LevelFatal) to only keep FATAL records.
 func GetLogLevelFromString(logLevel string) slog.Level {
 	switch strings.ToUpper(logLevel) {
 	case "DEBUG":
@@ -62,3 +72,39 @@ func getLogLevel(level *slog.Level) slog.Level {
 	}
 	return *level
 }
+
+// replaceAttrWithFatalLevel returns a slog.HandlerOptions.ReplaceAttr function which renders LevelFatal as "FATAL"
+// (or as a "CRITICAL" severity if gcp is true) and calls other (if not nil) for all other attributes.
+//
+// It must be chained after the user hook (see chainReplaceAttr) so that the hook always gets the original level.
+func replaceAttrWithFatalLevel(other func(groups []string, a slog.Attr) slog.Attr, gcp bool) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.LevelKey && len(groups) == 0 {
+			if level, ok := a.Value.Any().(slog.Level); ok && level == LevelFatal {
+				if gcp {
+					return slog.String("severity", "CRITICAL")
+				}
+				return slog.String(slog.LevelKey, "FATAL")
+			}
+		}
+		if other != nil {
+			return other(groups, a)
+		}
+		return a
+	}
+}
+
+// chainReplaceAttr returns a slog.HandlerOptions.ReplaceAttr function which calls first and then second
+// (if the attribute has not been removed), first can be nil.
+func chainReplaceAttr(first func(groups []string, a slog.Attr) slog.Attr, second func(groups []string, a slog.Attr) slog.Attr) func(groups []string, a slog.Attr) slog.Attr {
+	if first == nil {
+		return second
+	}
+	return func(groups []string, a slog.Attr) slog.Attr {
+		a = first(groups, a)
+		if a.Key == "" {
+			return a
+		}
+		return second(groups, a)
+	}
+}
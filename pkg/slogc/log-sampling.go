@@ -0,0 +1,42 @@
+package slogc
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/fabien-marty/slog-helpers/pkg/sampling"
+)
+
+// DefaultLogSamplingEnvVar is the default environment variable used to define the default sampling rules.
+//
+// The default value "LOG_SAMPLING" can be overridden with SetLogSamplingEnvVar.
+const DefaultLogSamplingEnvVar = "LOG_SAMPLING"
+
+var logSamplingEnvVarMutex = sync.RWMutex{}
+var logSamplingEnvVar = DefaultLogSamplingEnvVar
+
+// SetLogSamplingEnvVar sets the environment variable used to define the default sampling rules.
+func SetLogSamplingEnvVar(envVar string) {
+	logSamplingEnvVarMutex.Lock()
+	defer logSamplingEnvVarMutex.Unlock()
+	logSamplingEnvVar = envVar
+}
+
+// GetDefaultLogSamplingRules returns the default sampling rules.
+//
+// The default sampling rules are defined by the environment variable LOG_SAMPLING (see sampling.ParseRules for the syntax,
+// example: "info:100/s,debug:10/s"). If the environment variable is not set or empty, there is no sampling.
+func GetDefaultLogSamplingRules() ([]sampling.Rule, error) {
+	logSamplingEnvVarMutex.RLock()
+	defer logSamplingEnvVarMutex.RUnlock()
+	logSamplingAsString := strings.TrimSpace(os.Getenv(logSamplingEnvVar))
+	return sampling.ParseRules(logSamplingAsString)
+}
+
+func getSamplingRules(rules *[]sampling.Rule) ([]sampling.Rule, error) {
+	if rules == nil {
+		return GetDefaultLogSamplingRules()
+	}
+	return *rules, nil
+}
@@ -0,0 +1,80 @@
+package slogc
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/fabien-marty/slog-helpers/pkg/human"
+)
+
+// DefaultLogThemeEnvVar is the default environment variable used to define the default color theme (of the text-human format).
+//
+// The default value "LOG_THEME" can be overridden with SetLogThemeEnvVar.
+const DefaultLogThemeEnvVar = "LOG_THEME"
+
+// DefaultLogGlyphsEnvVar is the default environment variable used to define the default glyph set (of the text-human format).
+//
+// The default value "LOG_GLYPHS" can be overridden with SetLogGlyphsEnvVar.
+const DefaultLogGlyphsEnvVar = "LOG_GLYPHS"
+
+var logThemeEnvVarMutex = sync.RWMutex{}
+var logThemeEnvVar = DefaultLogThemeEnvVar
+var logGlyphsEnvVar = DefaultLogGlyphsEnvVar
+
+// SetLogThemeEnvVar sets the environment variable used to define the default color theme.
+func SetLogThemeEnvVar(envVar string) {
+	logThemeEnvVarMutex.Lock()
+	defer logThemeEnvVarMutex.Unlock()
+	logThemeEnvVar = envVar
+}
+
+// SetLogGlyphsEnvVar sets the environment variable used to define the default glyph set.
+func SetLogGlyphsEnvVar(envVar string) {
+	logThemeEnvVarMutex.Lock()
+	defer logThemeEnvVarMutex.Unlock()
+	logGlyphsEnvVar = envVar
+}
+
+// GetDefaultTheme returns the default color theme.
+//
+// The default theme is defined by the environment variable LOG_THEME with the name of a built-in theme ("default",
+// "solarized-dark", "solarized-light", "high-contrast" or "monochrome-bold", see human.Themes). If the environment
+// variable is not set, empty or invalid, human.ThemeDefault is returned.
+func GetDefaultTheme() *human.Theme {
+	logThemeEnvVarMutex.RLock()
+	defer logThemeEnvVarMutex.RUnlock()
+	theme := human.GetTheme(strings.TrimSpace(os.Getenv(logThemeEnvVar)))
+	if theme == nil {
+		return &human.ThemeDefault
+	}
+	return theme
+}
+
+// GetDefaultGlyphs returns the default glyph set.
+//
+// The default glyph set is defined by the environment variable LOG_GLYPHS ("ascii", "unicode" or "emoji").
+// If the environment variable is not set, empty or invalid, human.GlyphsUnicode is returned.
+func GetDefaultGlyphs() *human.Glyphs {
+	logThemeEnvVarMutex.RLock()
+	defer logThemeEnvVarMutex.RUnlock()
+	glyphs := human.GetGlyphs(strings.TrimSpace(os.Getenv(logGlyphsEnvVar)))
+	if glyphs == nil {
+		return &human.GlyphsUnicode
+	}
+	return glyphs
+}
+
+func getTheme(theme *human.Theme) *human.Theme {
+	if theme == nil {
+		return GetDefaultTheme()
+	}
+	return theme
+}
+
+func getGlyphs(glyphs *human.Glyphs) *human.Glyphs {
+	if glyphs == nil {
+		return GetDefaultGlyphs()
+	}
+	return glyphs
+}
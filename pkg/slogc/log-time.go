@@ -0,0 +1,95 @@
+package slogc
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fabien-marty/slog-helpers/pkg/human"
+)
+
+// DefaultLogTimeFormatEnvVar is the default environment variable used to define the default time format (of the text-human format).
+//
+// The default value "LOG_TIME_FORMAT" can be overridden with SetLogTimeFormatEnvVar.
+const DefaultLogTimeFormatEnvVar = "LOG_TIME_FORMAT"
+
+// DefaultLogTimeZoneEnvVar is the default environment variable used to define the default time zone (of the text-human format).
+//
+// The default value "LOG_TIME_ZONE" can be overridden with SetLogTimeZoneEnvVar.
+const DefaultLogTimeZoneEnvVar = "LOG_TIME_ZONE"
+
+var logTimeEnvVarMutex = sync.RWMutex{}
+var logTimeFormatEnvVar = DefaultLogTimeFormatEnvVar
+var logTimeZoneEnvVar = DefaultLogTimeZoneEnvVar
+
+// SetLogTimeFormatEnvVar sets the environment variable used to define the default time format.
+func SetLogTimeFormatEnvVar(envVar string) {
+	logTimeEnvVarMutex.Lock()
+	defer logTimeEnvVarMutex.Unlock()
+	logTimeFormatEnvVar = envVar
+}
+
+// SetLogTimeZoneEnvVar sets the environment variable used to define the default time zone.
+func SetLogTimeZoneEnvVar(envVar string) {
+	logTimeEnvVarMutex.Lock()
+	defer logTimeEnvVarMutex.Unlock()
+	logTimeZoneEnvVar = envVar
+}
+
+// GetTimeFormatFromString returns the time format (for human.Options.TimeFormat) from a string.
+//
+// Some aliases are case insensitive: "default" (or "s"), "ms" (or "milli"), "us" (or "micro"), "elapsed" (or "relative")
+// and "delta". Other strings are used as time layouts (see time.Format). If the string is empty, the default time format is returned.
+func GetTimeFormatFromString(timeFormat string) string {
+	switch strings.ToLower(timeFormat) {
+	case "", "default", "s":
+		return human.TimeFormatDefault
+	case "ms", "milli":
+		return human.TimeFormatMilli
+	case "us", "micro":
+		return human.TimeFormatMicro
+	case "elapsed", "relative":
+		return human.TimeFormatElapsed
+	case "delta":
+		return human.TimeFormatDelta
+	}
+	return timeFormat
+}
+
+// GetDefaultTimeFormat returns the default time format.
+//
+// The default time format is defined by the environment variable LOG_TIME_FORMAT (see GetTimeFormatFromString).
+func GetDefaultTimeFormat() string {
+	logTimeEnvVarMutex.RLock()
+	defer logTimeEnvVarMutex.RUnlock()
+	return GetTimeFormatFromString(strings.TrimSpace(os.Getenv(logTimeFormatEnvVar)))
+}
+
+// GetDefaultTimeLocation returns the default time location.
+//
+// The default time location is defined by the environment variable LOG_TIME_ZONE ("UTC", "Local" or an IANA
+// time zone name like "Europe/Paris"). If the environment variable is not set, empty or invalid, UTC is returned.
+func GetDefaultTimeLocation() *time.Location {
+	logTimeEnvVarMutex.RLock()
+	defer logTimeEnvVarMutex.RUnlock()
+	location, err := time.LoadLocation(strings.TrimSpace(os.Getenv(logTimeZoneEnvVar)))
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+func getTimeFormat(timeFormat *string) string {
+	if timeFormat == nil {
+		return GetDefaultTimeFormat()
+	}
+	return *timeFormat
+}
+
+func getTimeLocation(location *time.Location) *time.Location {
+	if location == nil {
+		return GetDefaultTimeLocation()
+	}
+	return location
+}
@@ -1,14 +1,24 @@
 package slogc
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"time"
 
+	"github.com/fabien-marty/slog-helpers/pkg/async"
+	"github.com/fabien-marty/slog-helpers/pkg/combined"
+	"github.com/fabien-marty/slog-helpers/pkg/contextattrs"
+	"github.com/fabien-marty/slog-helpers/pkg/dedup"
 	"github.com/fabien-marty/slog-helpers/pkg/external"
+	"github.com/fabien-marty/slog-helpers/pkg/fingerscrossed"
 	"github.com/fabien-marty/slog-helpers/pkg/human"
+	"github.com/fabien-marty/slog-helpers/pkg/sampling"
 	"github.com/fabien-marty/slog-helpers/pkg/stacktrace"
+	"github.com/fabien-marty/slog-helpers/pkg/tracecontext"
 	"github.com/mattn/go-isatty"
 	"github.com/vlad-tokarev/sloggcp"
 )
@@ -18,17 +28,47 @@ type loggerOptions struct {
 	_destination                     *LogDestination
 	_format                          *LogFormat
 	_stackTrace                      *bool
+	_stackTraceLevel                 *slog.Level
 	_colors                          *bool
+	_timeFormat                      *string
+	_timeLocation                    *time.Location
+	_theme                           *human.Theme
+	_glyphs                          *human.Glyphs
+	width                            int
+	attrsLayout                      human.AttrsLayout
+	prettyValues                     *bool
+	richErrors                       *bool
+	groupLayout                      human.GroupLayout
+	quoting                          human.Quoting
+	_samplingRules                   *[]sampling.Rule
+	dedupOptions                     *dedup.Options
+	fingersCrossedOptions            *fingerscrossed.Options
+	contextExtractors                []contextattrs.Extractor
+	traceContextAdapters             []tracecontext.Adapter
+	_traceContextStyle               *tracecontext.Style
 	destinationWriter                io.Writer
 	externalCallback                 external.Callback
 	externalFlattenedAttrsCallback   external.FlattenedAttrsCallback
 	externalStringifiedAttrsCallback external.StringifiedAttrsCallback
+	asyncOptions                     *async.Options
 	level                            slog.Level
 	destination                      LogDestination
 	format                           LogFormat
 	stackTrace                       bool
+	stackTraceLevel                  slog.Level
 	addSource                        bool
 	colors                           bool
+	timeFormat                       string
+	timeLocation                     *time.Location
+	theme                            *human.Theme
+	glyphs                           *human.Glyphs
+	samplingRules                    []sampling.Rule
+	traceContextStyle                tracecontext.Style
+
+	// given as is to the handlers (nil/empty means the handler default)
+	hyperlinks        *bool
+	editorURLTemplate string
+	replaceAttr       func(groups []string, a slog.Attr) slog.Attr
 }
 
 // LoggerOption is a type that defines the options for the logger.
@@ -57,6 +97,9 @@ func WithDestination(destination LogDestination) LoggerOption {
 // WithDestinationWriter is an option that sets the writer of the logger.
 //
 // Note: it overrides the destination set by WithDestination.
+//
+// The writer is flushed by (*Logger).Shutdown if it has a Flush() error method (bufio.Writer for example)
+// but it is never closed: the caller owns it and must close it (after Shutdown) if needed.
 func WithDestinationWriter(destinationWriter io.Writer) LoggerOption {
 	return func(options *loggerOptions) error {
 		options.destinationWriter = destinationWriter
@@ -73,6 +116,8 @@ func WithLogFormat(format LogFormat) LoggerOption {
 }
 
 // WithStackTrace is an option that sets if the logger should print or add stack traces.
+//
+// Even if stack traces are disabled, they are always added to FATAL records (see Fatal).
 func WithStackTrace(flag bool) LoggerOption {
 	return func(options *loggerOptions) error {
 		options._stackTrace = &flag
@@ -80,6 +125,15 @@ func WithStackTrace(flag bool) LoggerOption {
 	}
 }
 
+// WithStackTraceLevel is an option that sets the minimal level for which stack traces are automatically
+// printed or added (default to slog.LevelError, use LevelFatal to get them only for FATAL records).
+func WithStackTraceLevel(level slog.Level) LoggerOption {
+	return func(options *loggerOptions) error {
+		options._stackTraceLevel = &level
+		return nil
+	}
+}
+
 // WithColors is an option that sets if the logger should use colors.
 //
 // If not used, the use of colors is automatic (depending on the terminal connected to the logger destination).
@@ -90,6 +144,232 @@ func WithColors(flag bool) LoggerOption {
 	}
 }
 
+// WithTimeFormat is an option that sets the time format of the text-human format.
+//
+// See GetTimeFormatFromString for the possible values. If not used, the time format is defined by the LOG_TIME_FORMAT env var.
+func WithTimeFormat(timeFormat string) LoggerOption {
+	return func(options *loggerOptions) error {
+		timeFormat = GetTimeFormatFromString(timeFormat)
+		options._timeFormat = &timeFormat
+		return nil
+	}
+}
+
+// WithTimeLocation is an option that sets the time location (time zone) of the text-human format.
+//
+// If not used, the time location is defined by the LOG_TIME_ZONE env var (default to UTC).
+func WithTimeLocation(location *time.Location) LoggerOption {
+	return func(options *loggerOptions) error {
+		options._timeLocation = location
+		return nil
+	}
+}
+
+// WithTheme is an option that sets the color theme of the text-human format (for example &human.ThemeSolarizedLight).
+//
+// If not used, the theme is defined by the LOG_THEME env var (default to human.ThemeDefault).
+func WithTheme(theme *human.Theme) LoggerOption {
+	return func(options *loggerOptions) error {
+		options._theme = theme
+		return nil
+	}
+}
+
+// WithGlyphs is an option that sets the glyph set of the text-human format (for example &human.GlyphsASCII).
+//
+// If not used, the glyph set is defined by the LOG_GLYPHS env var (default to human.GlyphsUnicode).
+func WithGlyphs(glyphs *human.Glyphs) LoggerOption {
+	return func(options *loggerOptions) error {
+		options._glyphs = glyphs
+		return nil
+	}
+}
+
+// WithWidth is an option that sets the width used to wrap attributes in the text-human format (with colors).
+//
+// If not used, the width of the terminal is used (or the COLUMNS env var if set), a negative width disables wrapping.
+func WithWidth(width int) LoggerOption {
+	return func(options *loggerOptions) error {
+		options.width = width
+		return nil
+	}
+}
+
+// WithAttrsLayout is an option that sets the layout of attributes in the text-human format (with colors),
+// for example human.AttrsLayoutColumn to align keys in a column.
+func WithAttrsLayout(layout human.AttrsLayout) LoggerOption {
+	return func(options *loggerOptions) error {
+		options.attrsLayout = layout
+		return nil
+	}
+}
+
+// WithPrettyValues is an option that sets if structs, maps, slices and JSON strings should be rendered as indented
+// blocks in the text-human format.
+//
+// If not used, pretty values are enabled with colors only.
+func WithPrettyValues(flag bool) LoggerOption {
+	return func(options *loggerOptions) error {
+		options.prettyValues = &flag
+		return nil
+	}
+}
+
+// WithRichErrors is an option that sets if error values should be highlighted and rendered with the tree of their
+// causes (with types and stack traces) in the text-human format.
+//
+// If not used, rich errors are enabled with colors only.
+func WithRichErrors(flag bool) LoggerOption {
+	return func(options *loggerOptions) error {
+		options.richErrors = &flag
+		return nil
+	}
+}
+
+// WithGroupLayout is an option that sets how groups of attributes are rendered in the text-human format
+// (human.GroupLayoutFlat with dotted keys by default, human.GroupLayoutTree or human.GroupLayoutCompact).
+func WithGroupLayout(layout human.GroupLayout) LoggerOption {
+	return func(options *loggerOptions) error {
+		options.groupLayout = layout
+		return nil
+	}
+}
+
+// WithQuoting is an option that sets how attribute values are quoted in the text-human format
+// (human.QuotingLogfmt by default, human.QuotingAlways or human.QuotingNever).
+//
+// Whatever the quoting, control characters are always escaped (to prevent log injections).
+func WithQuoting(quoting human.Quoting) LoggerOption {
+	return func(options *loggerOptions) error {
+		options.quoting = quoting
+		return nil
+	}
+}
+
+// WithHyperlinks is an option that sets if source locations (and URLs) should be OSC 8 terminal hyperlinks
+// (only with colors in text-human and text formats).
+//
+// If not used, hyperlinks are enabled if the terminal advertises their support (FORCE_HYPERLINK=1 env var forces them).
+func WithHyperlinks(flag bool) LoggerOption {
+	return func(options *loggerOptions) error {
+		options.hyperlinks = &flag
+		return nil
+	}
+}
+
+// WithEditorURLTemplate is an option that sets the URL template of source location hyperlinks
+// (for example human.EditorURLTemplateVSCode, see WithHyperlinks).
+func WithEditorURLTemplate(template string) LoggerOption {
+	return func(options *loggerOptions) error {
+		options.editorURLTemplate = template
+		return nil
+	}
+}
+
+// WithReplaceAttr is an option that sets a slog.HandlerOptions.ReplaceAttr function (to rename or redact attributes)
+// used by all the log formats (including text-human and external).
+//
+// The function always gets the original level attribute (a slog.Level value with the slog.LevelKey key), the FATAL
+// and json-gcp renderings are applied to what it returns.
+func WithReplaceAttr(replaceAttr func(groups []string, a slog.Attr) slog.Attr) LoggerOption {
+	return func(options *loggerOptions) error {
+		options.replaceAttr = replaceAttr
+		return nil
+	}
+}
+
+// WithAsync is an option that makes the logger write records from a background goroutine through a bounded queue.
+//
+// queueSize is the maximum number of records waiting in the queue (0 means async.QueueSizeDefault)
+// and policy defines what to do when the queue is full (empty means async.PolicyDefault).
+// See the async package for details.
+func WithAsync(queueSize int, policy async.OverflowPolicy) LoggerOption {
+	return func(options *loggerOptions) error {
+		switch policy {
+		case "", async.PolicyBlock, async.PolicyDropNewest, async.PolicyDropOldest:
+		default:
+			return fmt.Errorf("unknown async overflow policy: %s", policy)
+		}
+		options.asyncOptions = &async.Options{
+			QueueSize: queueSize,
+			Policy:    policy,
+		}
+		return nil
+	}
+}
+
+// WithSampling is an option that limits the number of records per level (and per message) with the given rules.
+//
+// If not used, the rules are read from the LOG_SAMPLING environment variable (no sampling by default).
+// Use it without rule to disable sampling. See the sampling package for details.
+func WithSampling(rules ...sampling.Rule) LoggerOption {
+	return func(options *loggerOptions) error {
+		options._samplingRules = &rules
+		return nil
+	}
+}
+
+// WithDedup is an option that suppresses consecutive identical records and emits "repeated N times" summaries instead.
+//
+// window is the maximum duration of a streak of identical records (0 means dedup.WindowDefault).
+// See the dedup package for details.
+func WithDedup(window time.Duration) LoggerOption {
+	return func(options *loggerOptions) error {
+		options.dedupOptions = &dedup.Options{
+			Window: window,
+		}
+		return nil
+	}
+}
+
+// WithFingersCrossed is an option that buffers the records filtered by the logger level per unit of work
+// and outputs them only if a record with a level >= triggerLevel is logged in the same unit of work.
+//
+// Units of work are started with fingerscrossed.NewContext and records must be logged with the *Context methods
+// (DebugContext, InfoContext...). See the fingerscrossed package for details.
+func WithFingersCrossed(triggerLevel slog.Level) LoggerOption {
+	return func(options *loggerOptions) error {
+		options.fingersCrossedOptions = &fingerscrossed.Options{
+			TriggerLevel: triggerLevel,
+		}
+		return nil
+	}
+}
+
+// WithContextExtractor is an option that adds the attributes returned by the given function (called with the context
+// of the record) to every record logged with a context (InfoContext, ErrorContext...).
+//
+// It can be used several times. Extractors registered globally with contextattrs.Register are always used.
+// See the contextattrs package for details.
+func WithContextExtractor(extractor contextattrs.Extractor) LoggerOption {
+	return func(options *loggerOptions) error {
+		options.contextExtractors = append(options.contextExtractors, extractor)
+		return nil
+	}
+}
+
+// WithTraceContextAdapter is an option that adds an adapter to get the trace context of a record from
+// its context (for example from an OpenTelemetry span).
+//
+// It can be used several times (adapters are tried in order before the trace context stored with tracecontext.NewContext).
+// See the tracecontext package for details.
+func WithTraceContextAdapter(adapter tracecontext.Adapter) LoggerOption {
+	return func(options *loggerOptions) error {
+		options.traceContextAdapters = append(options.traceContextAdapters, adapter)
+		return nil
+	}
+}
+
+// WithTraceContextStyle is an option that sets the style of the trace context attributes (trace_id, span_id...).
+//
+// If not used, the style is tracecontext.StyleGCP for the json-gcp format and tracecontext.StyleDefault otherwise.
+func WithTraceContextStyle(style tracecontext.Style) LoggerOption {
+	return func(options *loggerOptions) error {
+		options._traceContextStyle = &style
+		return nil
+	}
+}
+
 func WithExternalCallback(callback external.Callback) LoggerOption {
 	return func(options *loggerOptions) error {
 		options.externalCallback = callback
@@ -111,7 +391,7 @@ func WithExternalStringifiedAttrsCallback(callback external.StringifiedAttrsCall
 	}
 }
 
-func completeOptions(options *loggerOptions) {
+func completeOptions(options *loggerOptions) error {
 	options.level = getLogLevel(options._level)
 	options.destination = getDestination(options._destination)
 	if options.destinationWriter == nil {
@@ -123,6 +403,11 @@ func completeOptions(options *loggerOptions) {
 	} else {
 		options.stackTrace = (options.level == slog.LevelDebug) && (options.format == LogFormatTextHuman)
 	}
+	if options._stackTraceLevel != nil {
+		options.stackTraceLevel = *options._stackTraceLevel
+	} else {
+		options.stackTraceLevel = stacktrace.MinimalLevelForStackTraceEnabledEnabledDefault
+	}
 	if options._colors != nil {
 		options.colors = *options._colors
 	} else {
@@ -133,42 +418,91 @@ func completeOptions(options *loggerOptions) {
 			options.colors = isatty.IsTerminal(file.Fd())
 		}
 	}
+	options.timeFormat = getTimeFormat(options._timeFormat)
+	options.timeLocation = getTimeLocation(options._timeLocation)
+	options.theme = getTheme(options._theme)
+	options.glyphs = getGlyphs(options._glyphs)
 	options.addSource = (options.level == slog.LevelDebug)
 	if options.externalCallback != nil || options.externalFlattenedAttrsCallback != nil || options.externalStringifiedAttrsCallback != nil {
 		options.format = LogFormatExternal // if an external callback is set, the format is forced to external
 	}
+	if options._traceContextStyle != nil {
+		options.traceContextStyle = *options._traceContextStyle
+	} else if options.format == LogFormatJsonGcp {
+		options.traceContextStyle = tracecontext.StyleGCP
+	} else {
+		options.traceContextStyle = tracecontext.StyleDefault
+	}
+	var err error
+	options.samplingRules, err = getSamplingRules(options._samplingRules)
+	return err
 }
 
-// GetLogger creates a new configured logger with the given options.
+// Logger is a configured *slog.Logger which also knows how to flush and close the components of its handler chain.
+//
+// You get it with New (GetLogger returns only the embedded *slog.Logger).
+type Logger struct {
+	*slog.Logger
+	lifecycle *lifecycle
+}
+
+// New creates a new configured logger with the given options.
+//
+// Contrary to GetLogger, it returns configuration errors instead of panicking and the returned Logger
+// provides a Shutdown method to call before exiting the program (to flush buffered records for example).
 //
 // Hint for your IDE: all LoggerOption functions starts with "With".
-func GetLogger(opts ...LoggerOption) *slog.Logger {
+func New(opts ...LoggerOption) (*Logger, error) {
 	var options loggerOptions
 	for _, opt := range opts {
 		err := opt(&options)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 	}
-	completeOptions(&options)
+	err := completeOptions(&options)
+	if err != nil {
+		return nil, err
+	}
 	standardHandlerOpts := slog.HandlerOptions{
-		Level:     options.level,
-		AddSource: options.addSource,
+		Level:       options.level,
+		AddSource:   options.addSource,
+		ReplaceAttr: options.replaceAttr,
 	}
+	lc := &lifecycle{}
 	var handler slog.Handler
 	switch options.format {
 	case LogFormatTextHuman:
 		handler = human.New(options.destinationWriter, &human.Options{
-			HandlerOptions: standardHandlerOpts,
-			UseColors:      options.colors,
+			HandlerOptions:    standardHandlerOpts,
+			UseColors:         options.colors,
+			TimeFormat:        options.timeFormat,
+			TimeLocation:      options.timeLocation,
+			Hyperlinks:        options.hyperlinks,
+			EditorURLTemplate: options.editorURLTemplate,
+			Theme:             options.theme,
+			Glyphs:            options.glyphs,
+			Width:             options.width,
+			AttrsLayout:       options.attrsLayout,
+			PrettyValues:      options.prettyValues,
+			RichErrors:        options.richErrors,
+			GroupLayout:       options.groupLayout,
+			Quoting:           options.quoting,
 		},
 		)
 	case LogFormatText:
+		standardHandlerOpts.ReplaceAttr = chainReplaceAttr(options.replaceAttr, replaceAttrWithFatalLevel(nil, false))
 		handler = slog.NewTextHandler(options.destinationWriter, &standardHandlerOpts)
+	case LogFormatCombined:
+		standardHandlerOpts.ReplaceAttr = chainReplaceAttr(options.replaceAttr, replaceAttrWithFatalLevel(nil, false))
+		handler = combined.New(options.destinationWriter, &combined.Options{
+			HandlerOptions: standardHandlerOpts,
+		})
 	case LogFormatJson:
+		standardHandlerOpts.ReplaceAttr = chainReplaceAttr(options.replaceAttr, replaceAttrWithFatalLevel(nil, false))
 		handler = slog.NewJSONHandler(options.destinationWriter, &standardHandlerOpts)
 	case LogFormatJsonGcp:
-		standardHandlerOpts.ReplaceAttr = sloggcp.ReplaceAttr
+		standardHandlerOpts.ReplaceAttr = chainReplaceAttr(options.replaceAttr, replaceAttrWithFatalLevel(sloggcp.ReplaceAttr, true))
 		handler = slog.NewJSONHandler(options.destinationWriter, &standardHandlerOpts)
 	case LogFormatExternal:
 		if options.externalCallback != nil {
@@ -187,32 +521,95 @@ func GetLogger(opts ...LoggerOption) *slog.Logger {
 				StringifiedCallback: options.externalStringifiedAttrsCallback,
 			})
 		} else {
-			panic("log format = external but no callback provided")
+			return nil, errors.New("log format = external but no callback provided")
 		}
 	default:
-		panic(fmt.Sprintf("unsupported log format: %s", options.format))
+		return nil, fmt.Errorf("unsupported log format: %s", options.format)
+	}
+	lc.add(options.destinationWriter)
+	if options.asyncOptions != nil {
+		handler = async.New(handler, options.asyncOptions)
+		lc.add(handler)
+	}
+	var mode stacktrace.Mode
+	switch options.format {
+	case LogFormatJsonGcp, LogFormatJson:
+		mode = stacktrace.ModeAddAttr
+	case LogFormatTextHuman, LogFormatText, LogFormatCombined:
+		if options.colors {
+			mode = stacktrace.ModePrintWithColors
+		} else {
+			mode = stacktrace.ModePrint
+		}
+	}
+	stackTraceOptions := &stacktrace.Options{
+		Mode:                                    mode,
+		HandlerOptions:                          standardHandlerOpts,
+		WriterForPrint:                          options.destinationWriter,
+		MinimalLevelForStackTraceEnabledEnabled: &options.stackTraceLevel,
+		Hyperlinks:                              options.hyperlinks,
+		EditorURLTemplate:                       options.editorURLTemplate,
 	}
 	if options.stackTrace {
-		var mode stacktrace.Mode
-		switch options.format {
-		case LogFormatJsonGcp, LogFormatJson:
-			mode = stacktrace.ModeAddAttr
-		case LogFormatTextHuman, LogFormatText:
-			if options.colors {
-				mode = stacktrace.ModePrintWithColors
-			} else {
-				mode = stacktrace.ModePrint
-			}
+		handler = stacktrace.New(handler, stackTraceOptions)
+	} else {
+		if options.format == LogFormatExternal {
+			// there is nothing to print to, the stack trace is given to the callback as an attribute
+			stackTraceOptions.Mode = stacktrace.ModeAddAttr
 		}
-		handler = stacktrace.New(handler, &stacktrace.Options{
-			Mode:           mode,
-			HandlerOptions: standardHandlerOpts,
-			WriterForPrint: options.destinationWriter,
-		},
-		)
+		// stack traces are always added to FATAL records (the add-stacktrace attribute is removed from other records)
+		handler = newFatalStackTraceHandler(handler, stackTraceOptions)
+	}
+	if options.fingersCrossedOptions != nil {
+		handler = fingerscrossed.New(handler, options.fingersCrossedOptions)
+	}
+	if options.dedupOptions != nil {
+		handler = dedup.New(handler, options.dedupOptions)
+		lc.add(handler)
+	}
+	if len(options.samplingRules) > 0 {
+		handler = sampling.New(handler, &sampling.Options{
+			Rules: options.samplingRules,
+		})
+		lc.add(handler)
+	}
+	traceContextExtractor := tracecontext.NewExtractor(&tracecontext.Options{
+		Style:        options.traceContextStyle,
+		Adapters:     options.traceContextAdapters,
+		GCPProjectID: os.Getenv("GOOGLE_CLOUD_PROJECT"),
+	})
+	handler = contextattrs.New(handler, &contextattrs.Options{
+		Extractors: append(options.contextExtractors, traceContextExtractor),
+	})
+	return &Logger{
+		Logger:    slog.New(&lifecycleHandler{Handler: handler, lifecycle: lc}),
+		lifecycle: lc,
+	}, nil
+}
+
+// Shutdown flushes and closes every component of the handler chain (from the outermost to the innermost).
+//
+// It must be called once before exiting the program (next calls return the result of the first one).
+// Records logged after Shutdown are still handled but synchronously.
+//
+// The destination is flushed but never closed (stdout/stderr are left open and a writer given
+// with WithDestinationWriter is owned by the caller).
+func (l *Logger) Shutdown(ctx context.Context) error {
+	return l.lifecycle.shutdown(ctx)
+}
+
+// GetLogger creates a new configured logger with the given options.
+//
+// It panics in case of configuration errors. See New if you want to handle them
+// or if you need to shut down the logger properly (when using WithAsync for example).
+//
+// Hint for your IDE: all LoggerOption functions starts with "With".
+func GetLogger(opts ...LoggerOption) *slog.Logger {
+	logger, err := New(opts...)
+	if err != nil {
+		panic(err)
 	}
-	logger := slog.New(handler)
-	return logger
+	return logger.Logger
 }
 
 // SetDefaultLogger configures a new logger and sets it as the default logger to be returned by slog.Default() calls or used by slog.Info/Debug/Warning/Error calls.
@@ -1,15 +1,25 @@
 package slogc
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/fabien-marty/slog-helpers/internal/ansi"
 	"github.com/fabien-marty/slog-helpers/internal/bufferpool"
+	"github.com/fabien-marty/slog-helpers/pkg/async"
 	"github.com/fabien-marty/slog-helpers/pkg/external"
+	"github.com/fabien-marty/slog-helpers/pkg/fingerscrossed"
+	"github.com/fabien-marty/slog-helpers/pkg/human"
 	"github.com/fabien-marty/slog-helpers/pkg/stacktrace"
+	"github.com/fabien-marty/slog-helpers/pkg/tracecontext"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -93,3 +103,248 @@ func TestGetLoggerExternal3(t *testing.T) {
 	}))
 	l.Warn("foo", slog.String("bar", "baz"))
 }
+
+func TestNewAsync(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	l, err := New(WithDestinationWriter(buffer), WithAsync(10, async.PolicyDropNewest))
+	assert.NoError(t, err)
+	l.Warn("foo", slog.String("bar", "baz"))
+	assert.NoError(t, l.Shutdown(context.Background()))
+	output := replaceDigits(buffer.String())
+	assert.Equal(t, "xxxx-xx-xxTxx:xx:xxZ [WARN ] foo {bar=baz}\n", output)
+}
+
+func TestGetLoggerAsyncBadPolicy(t *testing.T) {
+	assert.Panics(t, func() {
+		GetLogger(WithAsync(10, "foo"))
+	})
+}
+
+func TestNewErrors(t *testing.T) {
+	_, err := New(WithAsync(10, "foo"))
+	assert.Error(t, err)
+	_, err = New(WithLogFormat(LogFormatExternal))
+	assert.Error(t, err)
+}
+
+func TestShutdownFlushesWriter(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	writer := bufio.NewWriter(buffer)
+	l, err := New(WithDestinationWriter(writer), WithAsync(0, ""))
+	assert.NoError(t, err)
+	l.Info("foo")
+	assert.NoError(t, l.Shutdown(context.Background()))
+	assert.NoError(t, l.Shutdown(context.Background()))
+	assert.Equal(t, "xxxx-xx-xxTxx:xx:xxZ [INFO ] foo\n", replaceDigits(buffer.String()))
+}
+
+func TestNewSampling(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	t.Setenv(DefaultLogSamplingEnvVar, "info:1/h")
+	l, err := New(WithDestinationWriter(buffer))
+	assert.NoError(t, err)
+	l.Info("foo")
+	l.Info("foo")
+	l.Warn("bar")
+	l.Warn("bar")
+	assert.NoError(t, l.Shutdown(context.Background()))
+	lines := strings.Split(replaceDigits(buffer.String()), "\n")
+	assert.Equal(t, []string{
+		"xxxx-xx-xxTxx:xx:xxZ [INFO ] foo",
+		"xxxx-xx-xxTxx:xx:xxZ [WARN ] bar",
+		"xxxx-xx-xxTxx:xx:xxZ [WARN ] bar",
+		"xxxx-xx-xxTxx:xx:xxZ [WARN ] log records suppressed by sampling {sampling.level=INFO sampling.key=foo sampling.suppressed=x}",
+		"",
+	}, lines)
+	t.Setenv(DefaultLogSamplingEnvVar, "info:foo")
+	_, err = New(WithDestinationWriter(buffer))
+	assert.Error(t, err)
+}
+
+func TestNewDedup(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	l, err := New(WithDestinationWriter(buffer), WithDedup(time.Hour))
+	assert.NoError(t, err)
+	l.Warn("foo")
+	l.Warn("foo")
+	l.Warn("foo")
+	assert.NoError(t, l.Shutdown(context.Background()))
+	// (the trailing zeros of the nanoseconds are not rendered)
+	output := regexp.MustCompile(`\.\d+ `).ReplaceAllString(buffer.String(), ".n ")
+	lines := strings.Split(replaceDigits(output), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.Equal(t, "xxxx-xx-xxTxx:xx:xxZ [WARN ] foo", lines[0])
+	assert.Equal(t, `xxxx-xx-xxTxx:xx:xxZ [WARN ] foo {repeated=x repeated_first="xxxx-xx-xx xx:xx:xx.n +xxxx UTC" repeated_last="xxxx-xx-xx xx:xx:xx.n +xxxx UTC"}`, lines[1])
+}
+
+func TestNewFingersCrossed(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	l, err := New(WithDestinationWriter(buffer), WithFingersCrossed(slog.LevelWarn), WithStackTrace(false))
+	assert.NoError(t, err)
+	ctx := fingerscrossed.NewContext(context.Background())
+	l.DebugContext(ctx, "foo")
+	assert.Equal(t, 0, buffer.Len())
+	l.WarnContext(ctx, "bar")
+	assert.Equal(t, "xxxx-xx-xxTxx:xx:xxZ [DEBUG] foo\nxxxx-xx-xxTxx:xx:xxZ [WARN ] bar\n", replaceDigits(buffer.String()))
+}
+
+type requestIDKey struct{}
+
+func TestNewContextExtractor(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatJson), WithContextExtractor(func(ctx context.Context) []slog.Attr {
+		if requestID, ok := ctx.Value(requestIDKey{}).(string); ok {
+			return []slog.Attr{slog.String("request_id", requestID)}
+		}
+		return nil
+	}))
+	assert.NoError(t, err)
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "1234")
+	l.WithGroup("group").ErrorContext(ctx, "foo", slog.String("bar", "baz"))
+	var decoded map[string]any
+	assert.NoError(t, json.Unmarshal(buffer.Bytes(), &decoded))
+	assert.Equal(t, "1234", decoded["request_id"])
+	assert.Equal(t, map[string]any{"bar": "baz"}, decoded["group"])
+}
+
+func TestNewTraceContext(t *testing.T) {
+	tc, err := tracecontext.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.NoError(t, err)
+	ctx := tracecontext.NewContext(context.Background(), tc)
+	for format, key := range map[LogFormat]string{
+		LogFormatJson:    "trace_id",
+		LogFormatJsonGcp: "logging.googleapis.com/trace",
+	} {
+		buffer := bufferpool.Get()
+		l, err := New(WithDestinationWriter(buffer), WithLogFormat(format))
+		assert.NoError(t, err)
+		l.InfoContext(ctx, "foo")
+		var decoded map[string]any
+		assert.NoError(t, json.Unmarshal(buffer.Bytes(), &decoded))
+		assert.Contains(t, decoded[key], "4bf92f3577b34da6a3ce929d0e0e4736")
+		bufferpool.Put(buffer)
+	}
+}
+
+func TestNewTraceContextStyle(t *testing.T) {
+	adapter := tracecontext.AdapterFunc(func(ctx context.Context) (tracecontext.TraceContext, bool) {
+		return tracecontext.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Flags: tracecontext.FlagSampled}, true
+	})
+	for style, expected := range map[tracecontext.Style]map[string]any{
+		tracecontext.StyleDefault: {"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736", "span_id": "00f067aa0ba902b7"},
+		tracecontext.StyleGCP:     {"logging.googleapis.com/trace": "4bf92f3577b34da6a3ce929d0e0e4736", "logging.googleapis.com/spanId": "00f067aa0ba902b7"},
+		tracecontext.StyleECS:     {"trace": map[string]any{"id": "4bf92f3577b34da6a3ce929d0e0e4736"}, "span": map[string]any{"id": "00f067aa0ba902b7"}},
+		tracecontext.StyleOTel:    {"traceId": "4bf92f3577b34da6a3ce929d0e0e4736", "spanId": "00f067aa0ba902b7"},
+	} {
+		buffer := bufferpool.Get()
+		l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatJson), WithTraceContextStyle(style), WithTraceContextAdapter(adapter))
+		assert.NoError(t, err)
+		l.InfoContext(context.Background(), "foo")
+		var decoded map[string]any
+		assert.NoError(t, json.Unmarshal(buffer.Bytes(), &decoded))
+		for key, value := range expected {
+			assert.Equal(t, value, decoded[key], "style: %s, key: %s", style, key)
+		}
+		bufferpool.Put(buffer)
+	}
+}
+
+func TestNewTimeFormat(t *testing.T) {
+	t.Setenv(DefaultLogTimeFormatEnvVar, "ms")
+	t.Setenv(DefaultLogTimeZoneEnvVar, "Europe/Paris")
+	assert.Equal(t, human.TimeFormatMilli, GetDefaultTimeFormat())
+	assert.Equal(t, "Europe/Paris", GetDefaultTimeLocation().String())
+	assert.Equal(t, "15:04:05", GetTimeFormatFromString("15:04:05"))
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatTextHuman), WithColors(false), WithTimeFormat("us"), WithTimeLocation(time.UTC))
+	assert.NoError(t, err)
+	l.Info("foo")
+	assert.Regexp(t, `^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}Z \[INFO \] foo`, buffer.String())
+}
+
+func TestNewReplaceAttr(t *testing.T) {
+	redact := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == "password" {
+			return slog.String(a.Key, "REDACTED")
+		}
+		return a
+	}
+	for _, format := range []LogFormat{LogFormatTextHuman, LogFormatText, LogFormatJson, LogFormatJsonGcp} {
+		buffer := bufferpool.Get()
+		l, err := New(WithDestinationWriter(buffer), WithLogFormat(format), WithColors(false), WithReplaceAttr(redact))
+		assert.NoError(t, err)
+		l.Info("foo", slog.String("password", "secret"))
+		assert.Contains(t, buffer.String(), "REDACTED", format)
+		assert.NotContains(t, buffer.String(), "secret", format)
+		bufferpool.Put(buffer)
+	}
+}
+
+func TestNewTheme(t *testing.T) {
+	t.Setenv(DefaultLogThemeEnvVar, "high-contrast")
+	t.Setenv(DefaultLogGlyphsEnvVar, "ascii")
+	assert.Equal(t, &human.ThemeHighContrast, GetDefaultTheme())
+	assert.Equal(t, &human.GlyphsASCII, GetDefaultGlyphs())
+	t.Setenv(DefaultLogThemeEnvVar, "foo")
+	t.Setenv(DefaultLogGlyphsEnvVar, "")
+	assert.Equal(t, &human.ThemeDefault, GetDefaultTheme())
+	assert.Equal(t, &human.GlyphsUnicode, GetDefaultGlyphs())
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatTextHuman), WithColors(true), WithTheme(&human.ThemeSolarizedDark), WithGlyphs(&human.GlyphsASCII))
+	assert.NoError(t, err)
+	l.Info("foo")
+	assert.True(t, strings.HasPrefix(buffer.String(), "> "+human.ThemeSolarizedDark.Time))
+}
+
+func TestNewAttrsLayout(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatTextHuman), WithColors(true), WithTheme(&human.ThemeMonochromeBold), WithWidth(-1), WithAttrsLayout(human.AttrsLayoutColumn))
+	assert.NoError(t, err)
+	l.Info("foo", slog.String("a", "b"), slog.String("ccc", "d"))
+	assert.Contains(t, buffer.String(), "a"+ansi.Reset+"  =")
+}
+
+func TestNewPrettyValues(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatTextHuman), WithColors(false), WithPrettyValues(true))
+	assert.NoError(t, err)
+	l.Info("foo", slog.Any("map", map[string]int{"a": 1}))
+	assert.Contains(t, buffer.String(), "\n    map={\n      \"a\": 1\n    }\n")
+}
+
+func TestNewRichErrors(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatTextHuman), WithColors(false), WithRichErrors(true))
+	assert.NoError(t, err)
+	l.Warn("foo", slog.Any("err", fmt.Errorf("bar: %w", errors.New("baz"))))
+	assert.Contains(t, buffer.String(), "\n    err=bar: baz\n      *fmt.wrapError: bar\n      └─ *errors.errorString: baz\n")
+}
+
+func TestNewGroupLayout(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatTextHuman), WithColors(false), WithGroupLayout(human.GroupLayoutCompact))
+	assert.NoError(t, err)
+	l.Info("foo", slog.Group("http", slog.String("method", "GET"), slog.Int("status", 200)))
+	assert.Contains(t, buffer.String(), " foo {http{method=GET status=200}}\n")
+}
+
+func TestNewQuoting(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatTextHuman), WithColors(false), WithQuoting(human.QuotingAlways))
+	assert.NoError(t, err)
+	l.Info("foo\nbar", slog.String("a", "b"))
+	assert.Contains(t, buffer.String(), " foo\\nbar {a=\"b\"}\n")
+}
@@ -0,0 +1,34 @@
+//go:build unix
+
+package slogc
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fabien-marty/slog-helpers/internal/bufferpool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownOnSignals(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	exitCode := make(chan int, 1)
+	exit = func(code int) { exitCode <- code }
+	defer func() { exit = os.Exit }()
+	l, err := New(WithDestinationWriter(buffer), WithAsync(0, ""))
+	assert.NoError(t, err)
+	stop := l.ShutdownOnSignals(time.Second, syscall.SIGHUP)
+	defer stop()
+	l.Info("foo")
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	select {
+	case code := <-exitCode:
+		assert.Equal(t, 128+int(syscall.SIGHUP), code)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "exit function not called")
+	}
+	assert.Equal(t, "xxxx-xx-xxTxx:xx:xxZ [INFO ] foo\n", replaceDigits(buffer.String()))
+}
@@ -0,0 +1,96 @@
+package slogc
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+
+	"github.com/fabien-marty/slog-helpers/pkg/stacktrace"
+)
+
+// RecoverAction is an enumeration type that defines what RecoverAndLog does after logging the panic.
+type RecoverAction string
+
+// RecoverActionRepanic panics again with the recovered value (default).
+const RecoverActionRepanic RecoverAction = "repanic"
+
+// RecoverActionExit shuts down the logger (see Shutdown) and exits the program with the configured exit code.
+const RecoverActionExit RecoverAction = "exit"
+
+// RecoverActionSwallow does nothing more (the panic is stopped).
+const RecoverActionSwallow RecoverAction = "swallow"
+
+// RecoverMessageDefault is the default message of the records logged by RecoverAndLog.
+const RecoverMessageDefault = "panic recovered"
+
+// RecoverExitCodeDefault is the default exit code for RecoverActionExit (the same as the Go runtime for an unrecovered panic).
+const RecoverExitCodeDefault = 2
+
+// Keys of the attributes added by RecoverAndLog (and by HTTPMiddleware with KeyPanic).
+const (
+	KeyPanicType  = "panic_type"
+	KeyStackTrace = "stacktrace"
+)
+
+// RecoverOptions is a struct that contains the options for RecoverAndLog.
+type RecoverOptions struct {
+	Action   RecoverAction // What to do after logging the panic (default to RecoverActionRepanic).
+	ExitCode int           // The exit code for RecoverActionExit (default to RecoverExitCodeDefault).
+	Message  string        // The message of the record (default to RecoverMessageDefault).
+}
+
+// RecoverAndLog recovers a panic and logs it as an error record with the panic value, its type and the stack
+// of the panicking goroutine. Then it panics again, exits or swallows the panic depending on the options.
+//
+// It must be called directly with defer (at the beginning of a goroutine or of the main function):
+//
+//	defer slogc.RecoverAndLog(logger, nil)
+//
+// If logger is nil, slog.Default() is used. If opts is nil, default options are used.
+func RecoverAndLog(logger *slog.Logger, opts *RecoverOptions) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if opts == nil {
+		opts = &RecoverOptions{}
+	}
+	message := opts.Message
+	if message == "" {
+		message = RecoverMessageDefault
+	}
+	logger.Error(message,
+		slog.String(KeyPanic, fmt.Sprint(v)),
+		slog.String(KeyPanicType, fmt.Sprintf("%T", v)),
+		slog.String(KeyStackTrace, panickingStack(debug.Stack())),
+		slog.Bool(stacktrace.KeyForStackTraceEnabledDefault, false), // we already have a better stack trace
+	)
+	switch opts.Action {
+	case RecoverActionSwallow:
+		return
+	case RecoverActionExit:
+		code := opts.ExitCode
+		if code == 0 {
+			code = RecoverExitCodeDefault
+		}
+		shutdownAndExit(logger, code)
+	default:
+		panic(v)
+	}
+}
+
+// panickingStack removes the frames of the recover site (debug.Stack, the deferred function and panic)
+// from a stack returned by debug.Stack() called in a deferred function.
+func panickingStack(stack []byte) string {
+	lines := strings.Split(strings.TrimSuffix(string(stack), "\n"), "\n")
+	for i := 1; i+1 < len(lines); i++ {
+		if strings.HasPrefix(lines[i], "panic(") {
+			return strings.Join(append(lines[:1], lines[i+2:]...), "\n")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,70 @@
+package slogc
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func panicking() {
+	var m map[string]int
+	m["foo"] = 1
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	l, err := New(WithDestinationWriter(buffer), WithLogFormat(LogFormatJson), WithStackTrace(true))
+	assert.NoError(t, err)
+	func() {
+		defer RecoverAndLog(l.Logger, &RecoverOptions{Action: RecoverActionSwallow})
+		panicking()
+	}()
+	records := decodeLines(t, buffer)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "ERROR", records[0]["level"])
+	assert.Equal(t, RecoverMessageDefault, records[0]["msg"])
+	assert.Equal(t, "assignment to entry in nil map", records[0][KeyPanic])
+	assert.True(t, strings.HasPrefix(records[0][KeyPanicType].(string), "runtime."))
+	stack := records[0][KeyStackTrace].(string)
+	assert.True(t, strings.HasPrefix(stack, "goroutine "), stack)
+	assert.Contains(t, stack, "slogc.panicking(")
+	assert.NotContains(t, stack, "slogc.RecoverAndLog(")
+	_, ok := records[0]["add-stacktrace"]
+	assert.False(t, ok)
+}
+
+func TestRecoverAndLogWithoutStackTrace(t *testing.T) {
+	for _, format := range []LogFormat{LogFormatJson, LogFormatText} {
+		buffer := &bytes.Buffer{}
+		l, err := New(WithDestinationWriter(buffer), WithLogFormat(format), WithStackTrace(false))
+		assert.NoError(t, err)
+		func() {
+			defer RecoverAndLog(l.Logger, &RecoverOptions{Action: RecoverActionSwallow})
+			panicking()
+		}()
+		assert.Contains(t, buffer.String(), "slogc.panicking(", format) // the stack trace attribute is still there
+		assert.NotContains(t, buffer.String(), "add-stacktrace", format)
+	}
+}
+
+func TestRecoverAndLogActions(t *testing.T) {
+	assert.PanicsWithValue(t, "boom", func() {
+		defer RecoverAndLog(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)), nil)
+		panic("boom")
+	})
+	previous := exit
+	defer func() { exit = previous }()
+	code := 0
+	exit = func(c int) { code = c }
+	func() {
+		defer RecoverAndLog(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)), &RecoverOptions{Action: RecoverActionExit})
+		panic("boom")
+	}()
+	assert.Equal(t, RecoverExitCodeDefault, code)
+	func() {
+		defer RecoverAndLog(nil, nil) // no panic => nothing
+	}()
+}
@@ -7,6 +7,8 @@
 //   - a level greater or equal to slog.LevelError
 //   - (or) a boolean attribute add-stacktrace=true
 //
+// and never for records with a boolean attribute add-stacktrace=false (for example when the record already has its own stack trace).
+//
 // Note: in the default behavior, the attribute "add-stacktrace" will be automatically removed by this handler.
 //
 // The stacktrace can be added as an attribute to the record if the Mode is StackTraceHandlerOptions is ModeAddAttr (great for JSON format for example).
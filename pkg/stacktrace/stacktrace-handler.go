@@ -6,7 +6,11 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/fabien-marty/slog-helpers/internal/ansi"
 
@@ -53,6 +57,15 @@ const KeyNameForModeAddAttrDefault = "stacktrace"
 
 var mutex sync.Mutex
 
+// flusher is implemented by handlers which can delay the output of records (for example async.Handler).
+type flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// flushTimeout is the maximum time spent waiting for the record to be written before printing its stack trace
+// (after that, the stack trace is printed anyway), overridden in tests.
+var flushTimeout = time.Second
+
 func init() {
 	// not great to do this tuning here but tracerr API could be better IMHO
 	tracerr.DefaultIgnoreFirstFrames = 4
@@ -67,6 +80,8 @@ type Options struct {
 	KeyForStackTraceEnabled                 string      // The key of a boolean attribute to enable the stack trace
 	MinimalLevelForStackTraceEnabledEnabled *slog.Level // The minimal level for which the stack trace is automatically enabled.
 	WriterForPrint                          io.Writer   // The writer to use for ModePrint and ModePrintWithColors (default to stderr).
+	Hyperlinks                              *bool       // If true, file:line references are OSC 8 hyperlinks in ModePrintWithColors (nil => auto-detection of the terminal support).
+	EditorURLTemplate                       string      // The URL template of the hyperlinks, see human.EditorURLTemplate* constants (default to "file://{path}").
 }
 
 // Handler is a slog handler that adds a stack trace to the record (add attribute or print/write).
@@ -86,6 +101,10 @@ func New(originalHandler slog.Handler, options *Options) slog.Handler {
 	if options.KeyForStackTraceEnabled == "" {
 		options.KeyForStackTraceEnabled = KeyForStackTraceEnabledDefault
 	}
+	if options.Hyperlinks == nil {
+		hyperlinks := options.Mode == ModePrintWithColors && ansi.SupportsHyperlinks()
+		options.Hyperlinks = &hyperlinks
+	}
 	if options.MinimalLevelForStackTraceEnabledEnabled == nil {
 		defaultLevel := MinimalLevelForStackTraceEnabledEnabledDefault
 		options.MinimalLevelForStackTraceEnabledEnabled = &defaultLevel
@@ -106,17 +125,22 @@ func (sd *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 
 // StackTraceEnabled returns true if the stack trace must be added/printed.
 //
-// The default behavior is to add the stack trace for records with a level greater or equal to slog.LevelError.
+// The default behavior is to add the stack trace for records with a level greater or equal to slog.LevelError
+// (or with a boolean attribute add-stacktrace=true) unless the record has a boolean attribute add-stacktrace=false.
 // You can override this method to customize the behavior.
 //
 // Important note: the behavior of this
 func (sd *Handler) StackTraceEnabled(context context.Context, record *slog.Record) bool {
 	var stackStraceEnabled bool
+	var stackStraceDisabled bool
 	newRecord := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
 	record.Attrs(func(attr slog.Attr) bool {
 		if attr.Key == sd.opts.KeyForStackTraceEnabled {
-			if attr.Value.String() == "true" {
+			switch attr.Value.String() {
+			case "true":
 				stackStraceEnabled = true
+			case "false":
+				stackStraceDisabled = true
 			}
 			return true // we don't add this attribute to the new record
 		}
@@ -124,6 +148,9 @@ func (sd *Handler) StackTraceEnabled(context context.Context, record *slog.Recor
 		return true
 	})
 	*record = newRecord
+	if stackStraceDisabled {
+		return false
+	}
 	if sd.opts.MinimalLevelForStackTraceEnabledEnabled != nil && record.Level >= *sd.opts.MinimalLevelForStackTraceEnabledEnabled {
 		stackStraceEnabled = true
 	}
@@ -146,6 +173,17 @@ func (sd *Handler) beforeHandle(record *slog.Record) error {
 func (sd *Handler) afterHandle(slog.Record) error {
 	var str string
 	var err error
+	if sd.opts.Mode == ModePrint || sd.opts.Mode == ModePrintWithColors {
+		if f, ok := sd.Handler.(flusher); ok {
+			// the stack trace must be printed after the record itself
+			ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+			err = f.Flush(ctx)
+			cancel()
+			if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+				return err
+			}
+		}
+	}
 	switch sd.opts.Mode {
 	case ModePrint:
 		fakeErr := tracerr.Wrap(errors.New(""))
@@ -164,6 +202,9 @@ func (sd *Handler) afterHandle(slog.Record) error {
 		} else {
 			str = tracerr.Sprint(fakeErr)
 		}
+		if *sd.opts.Hyperlinks {
+			str = linkify(str, sd.opts.EditorURLTemplate)
+		}
 		mutex.Lock()
 		defer mutex.Unlock()
 		_, err = sd.opts.WriterForPrint.Write([]byte(ansi.RedBackground + ansi.White + "stacktrace enabled, let's print a stack trace" + ansi.Reset + "\n" + str + "\n"))
@@ -172,6 +213,25 @@ func (sd *Handler) afterHandle(slog.Record) error {
 	return err
 }
 
+// frameRegexp matches the frame lines of tracerr outputs ("/path/to/file.go:42 pkg.Func()", maybe in bold).
+var frameRegexp = regexp.MustCompile(`^(\x1b\[1m)?(/[^ \x1b]+\.go):(\d+) `)
+
+// linkify replaces the file:line references of frame lines by OSC 8 hyperlinks.
+func linkify(str string, editorURLTemplate string) string {
+	lines := strings.Split(str, "\n")
+	for i, line := range lines {
+		match := frameRegexp.FindStringSubmatchIndex(line)
+		if match == nil {
+			continue
+		}
+		path := line[match[4]:match[5]]
+		lineNumber, _ := strconv.Atoi(line[match[6]:match[7]])
+		link := ansi.Hyperlink(ansi.EditorURL(editorURLTemplate, path, lineNumber), line[match[4]:match[7]])
+		lines[i] = line[:match[4]] + link + line[match[7]:]
+	}
+	return strings.Join(lines, "\n")
+}
+
 // Handle forwards the call to the original handler (see constructor) and adds/prints the stack trace if needed.
 func (sd *Handler) Handle(context context.Context, record slog.Record) error {
 	var err error
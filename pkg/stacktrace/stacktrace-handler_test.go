@@ -1,13 +1,16 @@
 package stacktrace
 
 import (
+	"context"
 	"encoding/json"
+	"io"
 	"log/slog"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/fabien-marty/slog-helpers/internal/bufferpool"
+	"github.com/fabien-marty/slog-helpers/pkg/async"
 	"github.com/fabien-marty/slog-helpers/pkg/external"
 	"github.com/stretchr/testify/assert"
 )
@@ -126,3 +129,84 @@ func TestStackTraceHandlerWithAttr(t *testing.T) {
 	assert.True(t, ok)
 	assert.Greater(t, len(sstracktrace), 100)
 }
+
+func TestStackTraceHandlerDisabledWithAttr(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	jsonHandler := slog.NewJSONHandler(buffer, &slog.HandlerOptions{})
+	h := New(jsonHandler, &Options{
+		Mode: ModeAddAttr,
+	})
+	logger := slog.New(h)
+	logger.Error("hello error", slog.Bool("add-stacktrace", false))
+	r := record{}
+	err := json.Unmarshal(buffer.Bytes(), &r)
+	assert.NoError(t, err)
+	assert.Equal(t, "ERROR", r["level"])
+	_, ok := r["add-stacktrace"]
+	assert.False(t, ok)
+	_, ok = r["stacktrace"]
+	assert.False(t, ok)
+}
+
+func TestStackTraceHandlerPrintWithAsync(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	jsonHandler := slog.NewJSONHandler(buffer, &slog.HandlerOptions{})
+	asyncHandler := async.New(jsonHandler, &async.Options{})
+	defer asyncHandler.Close(context.Background())
+	h := New(asyncHandler, &Options{
+		Mode:           ModePrint,
+		WriterForPrint: buffer,
+	})
+	logger := slog.New(h)
+	logger.Error("hello error")
+	output := buffer.String()
+	assert.True(t, strings.HasPrefix(output, "{"))
+	assert.Contains(t, output, "}\nstacktrace enabled, let's print a stack trace\n")
+}
+
+type blockingHandler struct {
+	slog.Handler
+	blocker chan struct{}
+}
+
+func (bh *blockingHandler) Handle(ctx context.Context, record slog.Record) error {
+	<-bh.blocker
+	return bh.Handler.Handle(ctx, record)
+}
+
+func TestStackTraceHandlerPrintAsyncFlushTimeout(t *testing.T) {
+	flushTimeout = 10 * time.Millisecond
+	defer func() { flushTimeout = time.Second }()
+	buffer := &strings.Builder{}
+	blocked := &blockingHandler{Handler: slog.NewJSONHandler(io.Discard, nil), blocker: make(chan struct{})}
+	asyncHandler := async.New(blocked, &async.Options{})
+	defer asyncHandler.Close(context.Background())
+	defer close(blocked.blocker)
+	h := New(asyncHandler, &Options{
+		Mode:           ModePrint,
+		WriterForPrint: buffer,
+	})
+	assert.NoError(t, h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelError, "hello error", 0)))
+	assert.Contains(t, buffer.String(), "stacktrace enabled, let's print a stack trace\n") // printed anyway
+}
+
+func TestStackTraceHandlerHyperlinks(t *testing.T) {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	hyperlinks := true
+	h := New(slog.NewTextHandler(buffer, &slog.HandlerOptions{}), &Options{
+		Mode:              ModePrintWithColors,
+		WriterForPrint:    buffer,
+		Hyperlinks:        &hyperlinks,
+		EditorURLTemplate: "vscode://file{path}:{line}",
+	})
+	slog.New(h).Error("hello error")
+	assert.Regexp(t, "\x1b\\]8;;vscode://file/[^\x1b]+/stacktrace-handler_test\\.go:\\d+\x1b\\\\/[^\x1b]+/stacktrace-handler_test\\.go:\\d+\x1b\\]8;;\x1b\\\\ ", buffer.String())
+}
+
+func TestLinkify(t *testing.T) {
+	str := "error\n\x1b[1m/foo/bar.go:12 main.main()\x1b[0m\n12\tfoo()"
+	assert.Equal(t, "error\n\x1b[1m\x1b]8;;file:///foo/bar.go\x1b\\/foo/bar.go:12\x1b]8;;\x1b\\ main.main()\x1b[0m\n12\tfoo()", linkify(str, ""))
+}
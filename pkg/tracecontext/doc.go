@@ -0,0 +1,26 @@
+// tracecontext is a package to correlate log records with traces (W3C Trace Context, OpenTelemetry...) without hard dependency.
+//
+// The trace context of a record is found in its context.Context:
+//   - with the given Adapters (to get it from a tracing library, OpenTelemetry for example)
+//   - or stored with NewContext (for example by an HTTP middleware parsing the "traceparent" header with ParseTraceparent)
+//
+// NewExtractor returns a contextattrs.Extractor adding trace_id, span_id and trace_flags attributes (or their
+// native equivalent depending on the Style) to records logged with such a context.
+//
+// Example of an OpenTelemetry adapter (in your code):
+//
+//	adapter := AdapterFunc(func(ctx context.Context) (TraceContext, bool) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return TraceContext{}, false
+//		}
+//		return TraceContext{
+//			TraceID: sc.TraceID().String(),
+//			SpanID:  sc.SpanID().String(),
+//			Flags:   byte(sc.TraceFlags()),
+//		}, true
+//	})
+//	handler := contextattrs.New(slog.NewJSONHandler(os.Stderr, nil), &contextattrs.Options{
+//		Extractors: []contextattrs.Extractor{NewExtractor(&Options{Adapters: []Adapter{adapter}})},
+//	})
+package tracecontext
@@ -0,0 +1,175 @@
+package tracecontext
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/fabien-marty/slog-helpers/pkg/contextattrs"
+)
+
+// FlagSampled is the "sampled" trace flag.
+const FlagSampled byte = 0x01
+
+// TraceContext is a trace context (as defined by W3C Trace Context).
+type TraceContext struct {
+	TraceID string // The trace ID (32 lowercase hex characters).
+	SpanID  string // The span (parent) ID (16 lowercase hex characters).
+	Flags   byte   // The trace flags (see FlagSampled).
+}
+
+// IsValid returns true if the trace context has valid (and non zero) trace and span IDs.
+func (tc TraceContext) IsValid() bool {
+	return isValidID(tc.TraceID, 32) && isValidID(tc.SpanID, 16)
+}
+
+// Sampled returns true if the sampled flag is set.
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&FlagSampled != 0
+}
+
+// Traceparent returns the trace context formatted as a W3C "traceparent" header value.
+func (tc TraceContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", tc.TraceID, tc.SpanID, tc.Flags)
+}
+
+func isValidID(id string, length int) bool {
+	if len(id) != length || strings.Trim(id, "0") == "" {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTraceparent parses a W3C "traceparent" header value (for example "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01").
+func ParseTraceparent(traceparent string) (TraceContext, error) {
+	var tc TraceContext
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return tc, fmt.Errorf("invalid traceparent: %s", traceparent)
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return tc, fmt.Errorf("invalid traceparent flags: %s", traceparent)
+	}
+	tc = TraceContext{
+		TraceID: parts[1],
+		SpanID:  parts[2],
+		Flags:   flags[0],
+	}
+	if !tc.IsValid() {
+		return TraceContext{}, fmt.Errorf("invalid traceparent IDs: %s", traceparent)
+	}
+	return tc, nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx which carries the given trace context.
+func NewContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, contextKey{}, tc)
+}
+
+// FromContext returns the trace context stored in ctx with NewContext.
+func FromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(contextKey{}).(TraceContext)
+	return tc, ok
+}
+
+// Adapter is an interface to get the current trace context from a tracing library (OpenTelemetry for example).
+type Adapter interface {
+	TraceContext(ctx context.Context) (TraceContext, bool)
+}
+
+// AdapterFunc is a function implementing the Adapter interface.
+type AdapterFunc func(ctx context.Context) (TraceContext, bool)
+
+// TraceContext calls the function.
+func (f AdapterFunc) TraceContext(ctx context.Context) (TraceContext, bool) {
+	return f(ctx)
+}
+
+// Style is an enumeration type that defines where the trace context attributes are placed.
+type Style string
+
+// StyleDefault adds "trace_id", "span_id" and "trace_flags" attributes.
+const StyleDefault Style = "default"
+
+// StyleGCP adds the special fields of Google Cloud Logging ("logging.googleapis.com/trace"...).
+const StyleGCP Style = "gcp"
+
+// StyleECS adds the Elastic Common Schema fields ("trace.id" and "span.id" as groups).
+const StyleECS Style = "ecs"
+
+// StyleOTel adds the OpenTelemetry log data model fields ("traceId", "spanId" and "traceFlags").
+const StyleOTel Style = "otel"
+
+// Options is a struct that contains the options for NewExtractor.
+type Options struct {
+	Style        Style     // Where to place the attributes (default to StyleDefault).
+	Adapters     []Adapter // Adapters to get the trace context from tracing libraries (tried in order before FromContext).
+	GCPProjectID string    // The Google Cloud project ID (for StyleGCP, if empty the trace field is only the trace ID).
+}
+
+// Lookup returns the trace context of ctx (from the adapters, then from the value stored with NewContext).
+func (opts *Options) Lookup(ctx context.Context) (TraceContext, bool) {
+	for _, adapter := range opts.Adapters {
+		if tc, ok := adapter.TraceContext(ctx); ok && tc.IsValid() {
+			return tc, true
+		}
+	}
+	tc, ok := FromContext(ctx)
+	if !ok || !tc.IsValid() {
+		return TraceContext{}, false
+	}
+	return tc, true
+}
+
+// Attrs returns the trace context attributes (in the style of the options).
+func (opts *Options) Attrs(tc TraceContext) []slog.Attr {
+	switch opts.Style {
+	case StyleGCP:
+		trace := tc.TraceID
+		if opts.GCPProjectID != "" {
+			trace = "projects/" + opts.GCPProjectID + "/traces/" + tc.TraceID
+		}
+		return []slog.Attr{
+			slog.String("logging.googleapis.com/trace", trace),
+			slog.String("logging.googleapis.com/spanId", tc.SpanID),
+			slog.Bool("logging.googleapis.com/trace_sampled", tc.Sampled()),
+		}
+	case StyleECS:
+		return []slog.Attr{
+			slog.Group("trace", slog.String("id", tc.TraceID)),
+			slog.Group("span", slog.String("id", tc.SpanID)),
+		}
+	case StyleOTel:
+		return []slog.Attr{
+			slog.String("traceId", tc.TraceID),
+			slog.String("spanId", tc.SpanID),
+			slog.String("traceFlags", fmt.Sprintf("%02x", tc.Flags)),
+		}
+	}
+	return []slog.Attr{
+		slog.String("trace_id", tc.TraceID),
+		slog.String("span_id", tc.SpanID),
+		slog.String("trace_flags", fmt.Sprintf("%02x", tc.Flags)),
+	}
+}
+
+// NewExtractor returns a contextattrs.Extractor which returns the trace context attributes of the context (if any).
+func NewExtractor(opts *Options) contextattrs.Extractor {
+	return func(ctx context.Context) []slog.Attr {
+		tc, ok := opts.Lookup(ctx)
+		if !ok {
+			return nil
+		}
+		return opts.Attrs(tc)
+	}
+}
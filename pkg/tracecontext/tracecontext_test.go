@@ -0,0 +1,86 @@
+package tracecontext
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/fabien-marty/slog-helpers/pkg/contextattrs"
+	"github.com/stretchr/testify/assert"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	tc, err := ParseTraceparent(traceparent)
+	assert.NoError(t, err)
+	assert.Equal(t, TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Flags: 1}, tc)
+	assert.True(t, tc.Sampled())
+	assert.Equal(t, traceparent, tc.Traceparent())
+	// future versions can have more fields
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-foo")
+	assert.NoError(t, err)
+	for _, invalid := range []string{
+		"",
+		"foo",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-foo",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	} {
+		_, err = ParseTraceparent(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func decode(t *testing.T, style Style, ctx context.Context, adapters ...Adapter) map[string]any {
+	buffer := &strings.Builder{}
+	logger := slog.New(contextattrs.New(slog.NewJSONHandler(buffer, nil), &contextattrs.Options{
+		Registry:   contextattrs.NewRegistry(),
+		Extractors: []contextattrs.Extractor{NewExtractor(&Options{Style: style, Adapters: adapters, GCPProjectID: "my-project"})},
+	}))
+	logger.InfoContext(ctx, "hello")
+	var decoded map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(buffer.String()), &decoded))
+	return decoded
+}
+
+func TestExtractorStyles(t *testing.T) {
+	tc, _ := ParseTraceparent(traceparent)
+	ctx := NewContext(context.Background(), tc)
+	decoded := decode(t, StyleDefault, ctx)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", decoded["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", decoded["span_id"])
+	assert.Equal(t, "01", decoded["trace_flags"])
+	decoded = decode(t, StyleGCP, ctx)
+	assert.Equal(t, "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736", decoded["logging.googleapis.com/trace"])
+	assert.Equal(t, "00f067aa0ba902b7", decoded["logging.googleapis.com/spanId"])
+	assert.Equal(t, true, decoded["logging.googleapis.com/trace_sampled"])
+	decoded = decode(t, StyleECS, ctx)
+	assert.Equal(t, map[string]any{"id": "4bf92f3577b34da6a3ce929d0e0e4736"}, decoded["trace"])
+	assert.Equal(t, map[string]any{"id": "00f067aa0ba902b7"}, decoded["span"])
+	decoded = decode(t, StyleOTel, ctx)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", decoded["traceId"])
+	assert.Equal(t, "00f067aa0ba902b7", decoded["spanId"])
+	assert.Equal(t, "01", decoded["traceFlags"])
+	decoded = decode(t, StyleDefault, context.Background())
+	assert.NotContains(t, decoded, "trace_id")
+}
+
+func TestExtractorAdapters(t *testing.T) {
+	fromAdapter := TraceContext{TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"}
+	invalid := AdapterFunc(func(ctx context.Context) (TraceContext, bool) { return TraceContext{TraceID: "foo"}, true })
+	none := AdapterFunc(func(ctx context.Context) (TraceContext, bool) { return TraceContext{}, false })
+	valid := AdapterFunc(func(ctx context.Context) (TraceContext, bool) { return fromAdapter, true })
+	tc, _ := ParseTraceparent(traceparent)
+	ctx := NewContext(context.Background(), tc)
+	decoded := decode(t, StyleDefault, ctx, invalid, none, valid)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", decoded["trace_id"])
+	assert.Equal(t, "00", decoded["trace_flags"])
+	decoded = decode(t, StyleDefault, ctx, invalid, none)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", decoded["trace_id"])
+}